@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/antonmedv/expr"
+)
+
+// AutoPlay plays up to maxTurns turns, each time making the offered choice
+// that maximizes the numeric goal expression, e.g. "World.Powers.Legislation".
+// Random deltas are assumed to take their expected value when comparing
+// choices, and choices the engine can't afford are skipped. It returns the
+// choices made and the final world.
+func AutoPlay(engine *Engine, goal string, maxTurns int) ([]Choice, World, error) {
+	return AutoPlayWithOptions(engine, goal, maxTurns, AutoPlayOptions{})
+}
+
+// AutoPlayOptions configures AutoPlayWithOptions.
+type AutoPlayOptions struct {
+	// Temperature, if positive, makes the player sample choices with
+	// probability proportional to exp(score/Temperature) using the engine's
+	// random number generator, rather than always making the best one. The
+	// lower the temperature, the closer to the best choice.
+	Temperature float64
+}
+
+// AutoPlayWithOptions is AutoPlay configured by opts.
+func AutoPlayWithOptions(engine *Engine, goal string, maxTurns int, opts AutoPlayOptions) ([]Choice, World, error) {
+	node, err := expr.Parse(goal, expr.Define("World", World{}))
+	if err != nil {
+		return nil, World{}, fmt.Errorf("invalid goal %q: %v", goal, err)
+	}
+
+	var history []Choice
+	for turn := 0; turn < maxTurns; turn++ {
+		var choices []Choice
+		for _, choice := range offeredChoices(engine.Decisions()) {
+			if engine.CanAfford(choice) {
+				choices = append(choices, choice)
+			}
+		}
+		if len(choices) == 0 {
+			break
+		}
+		var choice Choice
+		if opts.Temperature > 0 {
+			choice, err = softmaxChoice(node, engine.Current(), choices, opts.Temperature, engine.rand)
+		} else {
+			choice, err = bestChoice(node, engine.Current(), choices)
+		}
+		if err != nil {
+			return history, engine.Current(), err
+		}
+		if err := engine.Choose(choice); err != nil {
+			return history, engine.Current(), err
+		}
+		history = append(history, choice)
+	}
+	return history, engine.Current(), nil
+}
+
+// bestChoice returns the first of choices scoring highest on goal.
+func bestChoice(goal expr.Node, world World, choices []Choice) (Choice, error) {
+	scores, err := scoreChoices(goal, world, choices)
+	if err != nil {
+		return Choice{}, err
+	}
+	best := 0
+	for i, score := range scores {
+		if score > scores[best] {
+			best = i
+		}
+	}
+	return choices[best], nil
+}
+
+// softmaxChoice samples one of choices with probability proportional to
+// exp(score/temperature).
+func softmaxChoice(goal expr.Node, world World, choices []Choice, temperature float64, r Rand) (Choice, error) {
+	scores, err := scoreChoices(goal, world, choices)
+	if err != nil {
+		return Choice{}, err
+	}
+	// Subtracting the best score keeps exp from overflowing.
+	best := math.Inf(-1)
+	for _, score := range scores {
+		best = math.Max(best, score)
+	}
+	weights := make([]float64, len(scores))
+	total := 0.0
+	for i, score := range scores {
+		weights[i] = math.Exp((score - best) / temperature)
+		total += weights[i]
+	}
+	x := r.Float64() * total
+	for i, weight := range weights {
+		if x < weight {
+			return choices[i], nil
+		}
+		x -= weight
+	}
+	// Guard against rounding errors.
+	return choices[len(choices)-1], nil
+}
+
+// scoreChoices evaluates goal on the world resulting from each choice.
+func scoreChoices(goal expr.Node, world World, choices []Choice) ([]float64, error) {
+	scores := make([]float64, len(choices))
+	for i, choice := range choices {
+		w, _, err := world.ApplyPreview(choice)
+		if err != nil {
+			return nil, err
+		}
+		score, err := evalNumber(goal, w)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating goal: %v", err)
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
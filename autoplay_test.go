@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antonmedv/expr"
+)
+
+func TestAutoPlay(t *testing.T) {
+	tests := []struct {
+		goal  string
+		turns int
+		want  []string
+		money int
+	}{
+		{"World.Resources.Money", 3, []string{"Raise", "Raise", "Raise"}, 4300},
+		{"-World.Resources.Money", 2, []string{"Lower", "Lower"}, 3800},
+		{"-(World.Resources.Money - 4050) * (World.Resources.Money - 4050)", 2, []string{"Raise", "Lower"}, 4000},
+		{"World.Resources.Money", 0, nil, 4000},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		history, world, err := AutoPlay(e, test.goal, test.turns)
+		if err != nil {
+			t.Fatalf("%v: %v", test.goal, err)
+		}
+		got := make([]string, len(history))
+		for i, choice := range history {
+			got[i] = choice.Description
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got choices %v, want %v", test.goal, got, test.want)
+		}
+		if world.Resources["Money"] != test.money {
+			t.Errorf("%v: got Money %v, want %v", test.goal, world.Resources["Money"], test.money)
+		}
+	}
+}
+
+func TestAutoPlayErrors(t *testing.T) {
+	tests := []struct {
+		goal string
+		err  string
+	}{
+		{"World.Resources.Money >", `invalid goal "World.Resources.Money >"`},
+		{`"rich"`, "evaluating goal: expected a number, got string"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = AutoPlay(e, test.goal, 1)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.goal, err, test.err)
+		}
+	}
+}
+
+func TestSoftmaxChoice(t *testing.T) {
+	goal, err := expr.Parse("World.Resources.Money", expr.Define("World", World{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := World{Resources: map[string]int{"Money": 4000}}
+	choices := taxScenario(t).Rules[0].Choices
+	tests := []struct {
+		temperature float64
+		r           float64
+		want        string
+	}{
+		// The weights are 1 for Raise and exp(-2) for Lower.
+		{100, 0.5, "Raise"},
+		{100, 0.85, "Raise"},
+		{100, 0.9, "Lower"},
+		// Both are almost as likely.
+		{1e9, 0.45, "Raise"},
+		{1e9, 0.55, "Lower"},
+		// Lower is practically never picked.
+		{1, 0.999, "Raise"},
+	}
+	for _, test := range tests {
+		choice, err := softmaxChoice(goal, world, choices, test.temperature, fixedRand(test.r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if choice.Description != test.want {
+			t.Errorf("temperature %v, r %v: got %v, want %v", test.temperature, test.r, choice.Description, test.want)
+		}
+	}
+}
+
+func TestAutoPlayTemperature(t *testing.T) {
+	play := func(temperature float64) []string {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		history, _, err := AutoPlayWithOptions(e, "World.Resources.Money", 20, AutoPlayOptions{Temperature: temperature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(history))
+		for i, choice := range history {
+			got[i] = choice.Description
+		}
+		return got
+	}
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	greedy, _, err := AutoPlay(e, "World.Resources.Money", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]string, len(greedy))
+	for i, choice := range greedy {
+		want[i] = choice.Description
+	}
+	if got := play(0); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v at temperature 0, want %v as greedy", got, want)
+	}
+	if got := play(1000); !strings.Contains(fmt.Sprint(got), "Lower") {
+		t.Errorf("got %v at temperature 1000, want some non-optimal choices", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import "fmt"
+
+// ChooseMany makes several choices at once, e.g. to enact independent
+// policies in the same turn, applying them in order and then starting the
+// next turn. The choices must come from Decisions, each from a different
+// decision, and have no follow-up decision; like with Choose, the offered
+// choices are applied. Nothing is changed if a choice isn't offered, if two
+// choices conflict, i.e. change the same resource or power in ways whose
+// result depends on their order, or if together they overspend the budget.
+// If ending the turn fails, the engine is rolled back to where it was.
+func (e *Engine) ChooseMany(choices []Choice) error {
+	defer e.enter()()
+	if len(e.decisions) == 0 {
+		return fmt.Errorf("game is over")
+	}
+	if len(choices) == 0 {
+		return fmt.Errorf("no choices")
+	}
+
+	// The choices are applied to a copy so that nothing changes if one
+	// fails, each seeing the world left by the previous ones.
+	undo := e.snapshot()
+	restore := e.checkpoint()
+	fail := func(err error) error {
+		restore()
+		return err
+	}
+	used := make(map[int]bool, len(choices))
+	offered := make([]Choice, len(choices))
+	decisions := make([]Decision, len(choices))
+	changes := make([]Change, len(choices))
+	befores := make([]World, len(choices))
+	world := e.world.Copy()
+	for i, choice := range choices {
+		j, index, ok := e.decisionOf(choice)
+		if !ok {
+			return fail(fmt.Errorf("choice %v is not offered", choice.Description))
+		}
+		decision := e.decisions[j]
+		if used[j] {
+			return fail(fmt.Errorf("choice %v: decision %v is already chosen", choice.Description, decision.Description))
+		}
+		used[j] = true
+		if e.expired(decision) {
+			return fail(fmt.Errorf("decision %v expired", decision.Description))
+		}
+		choice = decision.Choices[index]
+		if choice.Next != nil {
+			return fail(fmt.Errorf("choice %v has a follow-up decision", choice.Description))
+		}
+		offered[i], decisions[i] = choice, decision
+
+		change, err := choice.change(world)
+		if err != nil {
+			return fail(err)
+		}
+		for k, other := range changes[:i] {
+			if key, ok := conflict(other, change); ok {
+				return fail(fmt.Errorf("choices %v and %v conflict over %v", offered[k].Description, choice.Description, key))
+			}
+		}
+		changes[i] = change
+
+		if !affordable(world, choice, e.cfg.BudgetResource) {
+			return fail(fmt.Errorf("can't afford %v", choice.Description))
+		}
+		befores[i] = world.Copy()
+		if err := world.Apply(choice, e.rand); err != nil {
+			return fail(err)
+		}
+	}
+
+	e.push(undo)
+	for _, decision := range decisions {
+		e.settle(decision)
+	}
+	if e.chain == 0 {
+		e.past.push(e.world)
+	}
+	e.world = world
+	for i, choice := range offered {
+		choice := choice
+		e.record(choice)
+		e.emit(Event{Kind: ChoiceApplied, Before: befores[i], Choice: &choice})
+	}
+	if err := e.endTurn(); err != nil {
+		return fail(err)
+	}
+	if err := e.conclude(nil); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// decisionOf returns the index of the offered decision choice belongs to
+// and that of the choice in it. Choices are identified by the position
+// they were offered at, their description and rule guarding against
+// choices offered before.
+func (e *Engine) decisionOf(choice Choice) (int, int, bool) {
+	i, j := choice.offered.decision-1, choice.offered.choice
+	if i < 0 || i >= len(e.decisions) || j >= len(e.decisions[i].Choices) {
+		return 0, 0, false
+	}
+	if offered := e.decisions[i].Choices[j]; offered.Description != choice.Description || offered.rule != choice.rule {
+		return 0, 0, false
+	}
+	return i, j, true
+}
+
+// conflict returns a resource or power both a and b change in ways whose
+// result depends on the order they are applied in.
+func conflict(a, b Change) (string, bool) {
+	for _, deltas := range []struct{ a, b map[string]Delta }{
+		{a.Resources, b.Resources},
+		{a.Powers, b.Powers},
+	} {
+		for _, key := range sortedDeltaKeys(deltas.a) {
+			if delta, ok := deltas.b[key]; ok && !commute(deltas.a[key], delta) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// deltaKind classifies deltas by how they combine.
+type deltaKind int
+
+const (
+	otherDelta deltaKind = iota
+	// additiveDelta only adds to the value.
+	additiveDelta
+	// multiplicativeDelta only multiplies the value.
+	multiplicativeDelta
+)
+
+func (d Delta) kind() deltaKind {
+	switch d.Op() {
+	case OpAdd, OpAddRandom:
+		return additiveDelta
+	case OpMul:
+		return multiplicativeDelta
+	case OpAddPercent:
+		if d[1] == 0 {
+			return multiplicativeDelta
+		}
+	case OpLinear:
+		if d[0] == 1 {
+			return additiveDelta
+		}
+		if d[1] == 0 {
+			return multiplicativeDelta
+		}
+	}
+	return otherDelta
+}
+
+// commute reports whether applying a then b gives the same result as b
+// then a, rounding aside.
+func commute(a, b Delta) bool {
+	kind := a.kind()
+	return kind != otherDelta && kind == b.kind()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChooseMany(t *testing.T) {
+	add := func(v float64) Delta { return Delta{v, 0, float64(OpAdd)} }
+	decision := func(description string, choices ...Choice) Decision {
+		return Decision{Description: description, Choices: choices}
+	}
+	rich, err := NewGuard("World.Resources.Money > 4050")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "tax", "true", 1, decision("Tax",
+			Choice{Description: "Raise", Change: Change{Resources: map[string]Delta{"Money": add(100)}}},
+			Choice{Description: "Lower", Change: Change{Resources: map[string]Delta{"Money": add(-100)}}},
+		)),
+		mustRule(t, "army", "true", 1, decision("Army",
+			Choice{Description: "Recruit", Change: Change{Powers: map[string]Delta{"Military": add(10)}}},
+			Choice{Description: "Levy", Change: Change{Resources: map[string]Delta{"Money": add(50)}}},
+		)),
+		mustRule(t, "mint", "true", 1, decision("Mint",
+			Choice{Description: "Print", Change: Change{Resources: map[string]Delta{"Money": {2, 0, float64(OpMul)}}}},
+			Choice{Description: "Debase", Change: Change{Resources: map[string]Delta{"Money": {0.5, 0}}}},
+		)),
+		mustRule(t, "reform", "true", 1, decision("Reform",
+			Choice{
+				Description: "Inflate",
+				Change:      Change{Powers: map[string]Delta{"Military": add(5)}},
+				Branches:    []Branch{{Guard: rich, Change: Change{Resources: map[string]Delta{"Money": {2, 0, float64(OpMul)}}}}},
+			},
+		)),
+	}}
+	tests := []struct {
+		name     string
+		choices  []string
+		money    int
+		military int
+		err      string
+	}{
+		{name: "one", choices: []string{"Raise"}, money: 4100, military: 90},
+		{name: "independent", choices: []string{"Raise", "Recruit"}, money: 4100, military: 100},
+		{name: "additive", choices: []string{"Raise", "Levy"}, money: 4150, military: 90},
+		{name: "multiplicative", choices: []string{"Levy", "Print"}, err: "choices Levy and Print conflict over Money"},
+		{name: "mixed", choices: []string{"Raise", "Debase"}, err: "choices Raise and Debase conflict over Money"},
+		{name: "same decision", choices: []string{"Raise", "Lower"}, err: "choice Lower: decision Tax is already chosen"},
+		{name: "branch", choices: []string{"Lower", "Inflate"}, money: 3900, military: 95},
+		{name: "branch after a change", choices: []string{"Raise", "Inflate"}, err: "choices Raise and Inflate conflict over Money"},
+		{name: "none", choices: nil, err: "no choices"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxDecisions: 4, MaxUndo: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		choices := make([]Choice, len(test.choices))
+		for i, description := range test.choices {
+			choices[i] = findChoice(t, e.Decisions(), description)
+		}
+		before := e.Current()
+		err = e.ChooseMany(choices)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+			}
+			if !reflect.DeepEqual(withoutPast(e.Current()), withoutPast(before)) {
+				t.Errorf("%v: got world %+v after an error, want %+v", test.name, e.Current(), before)
+			}
+			if err := e.Undo(); err == nil {
+				t.Errorf("%v: got a turn to undo after an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		world := e.Current()
+		if world.Turn != 1 || world.Resources["Money"] != test.money || world.Powers["Military"] != test.military {
+			t.Errorf("%v: got turn %v, Money %v and Military %v, want 1, %v and %v", test.name, world.Turn, world.Resources["Money"], world.Powers["Military"], test.money, test.military)
+		}
+		if got := e.RuleStats(); len(got) != len(test.choices) {
+			t.Errorf("%v: got rule stats %v, want %v rules fired", test.name, got, len(test.choices))
+		}
+	}
+}
+
+func TestChooseManyNotOffered(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.ChooseMany([]Choice{e.Decisions()[0].Choices[0], {Description: "Abdicate"}})
+	if err == nil || err.Error() != "choice Abdicate is not offered" {
+		t.Errorf("got error %v, want choice Abdicate is not offered", err)
+	}
+}
+
+func TestChooseManySameDescriptions(t *testing.T) {
+	accept := func(key string) Decision {
+		return Decision{Description: key, Choices: []Choice{{
+			Description: "Accept",
+			Change:      Change{Resources: map[string]Delta{key: {1, 1}}},
+		}}}
+	}
+	scenario := Scenario{
+		Scheduled:       []ScheduledEvent{{Turn: 0, Decision: accept("Money")}},
+		AlwaysAvailable: []Decision{accept("Gold"), accept("Grain")},
+		InitialWorld:    &World{Resources: map[string]int{"Money": 10, "Gold": 10, "Grain": 10}},
+	}
+	tests := []struct {
+		name    string
+		offered []int
+		want    map[string]int
+		err     string
+	}{
+		{name: "scheduled and always available", offered: []int{0, 1}, want: map[string]int{"Money": 11, "Gold": 11, "Grain": 10}},
+		{name: "always available", offered: []int{2, 1}, want: map[string]int{"Money": 10, "Gold": 11, "Grain": 11}},
+		{name: "same decision", offered: []int{1, 1}, err: "choice Accept: decision Gold is already chosen"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		choices := make([]Choice, len(test.offered))
+		for i, j := range test.offered {
+			choices[i] = e.Decisions()[j].Choices[0]
+		}
+		err = e.ChooseMany(choices)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if got := e.Current().Resources; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// findChoice returns the choice with the given description among decisions.
+func findChoice(t *testing.T, decisions []Decision, description string) Choice {
+	t.Helper()
+	for _, decision := range decisions {
+		for _, choice := range decision.Choices {
+			if choice.Description == description {
+				return choice
+			}
+		}
+	}
+	t.Fatalf("choice %q not offered in %v", description, descriptions(decisions))
+	return Choice{}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// binaryVersion is the first byte of the states saved by MarshalBinary.
+const binaryVersion = 1
+
+// MarshalBinary is a compact alternative to SaveState, saving the same
+// state: numbers are varints and maps are written as sorted key-value pairs.
+func (e *Engine) MarshalBinary() ([]byte, error) {
+	saved := e.saved()
+	w := &binaryWriter{}
+	w.WriteByte(binaryVersion)
+	w.world(saved.World)
+	w.int(saved.Seed)
+	w.uint(saved.Draws)
+	w.intMap(saved.LastFired)
+	w.intMap(saved.Fires)
+	w.uint(uint64(len(saved.Chosen)))
+	for _, chosen := range saved.Chosen {
+		w.string(chosen.Rule)
+		w.string(chosen.Choice)
+	}
+	if saved.Result == nil {
+		w.WriteByte(0)
+	} else {
+		w.WriteByte(1)
+		w.int(int64(saved.Result.Outcome))
+		w.string(saved.Result.Condition)
+	}
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary resumes a game saved with MarshalBinary like LoadState
+// does. e must have been created with the scenario and cfg of the game;
+// its event handlers and subscribers are kept, while its undo history is
+// cleared.
+func (e *Engine) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{Reader: bytes.NewReader(data)}
+	version := r.byte()
+	if r.err == nil && version != binaryVersion {
+		return fmt.Errorf("invalid saved state: unknown version %v", version)
+	}
+	var saved savedState
+	saved.World = r.world()
+	saved.Seed = r.int()
+	saved.Draws = r.uint()
+	saved.LastFired = r.intMap()
+	saved.Fires = r.intMap()
+	n := r.uint()
+	// Each choice takes at least two bytes.
+	if n > uint64(r.Len()) {
+		r.fail(io.ErrUnexpectedEOF)
+		n = 0
+	}
+	for i := uint64(0); i < n; i++ {
+		rule := r.string()
+		saved.Chosen = append(saved.Chosen, ChosenChoice{Rule: rule, Choice: r.string()})
+	}
+	if r.byte() == 1 {
+		saved.Result = &GameResult{Outcome: Outcome(r.int()), Condition: r.string()}
+	}
+	if r.err == nil && r.Len() > 0 {
+		r.err = errors.New("trailing data")
+	}
+	if r.err != nil {
+		return fmt.Errorf("invalid saved state: %v", r.err)
+	}
+
+	restored, err := restore(saved, e.scenario, e.cfg)
+	if err != nil {
+		return err
+	}
+	handlers, subscribers, lastSubscriber := e.handlers, e.subscribers, e.lastSubscriber
+	*e = *restored
+	e.world.past = &e.past
+	e.world.state = &e.state
+	e.handlers, e.subscribers, e.lastSubscriber = handlers, subscribers, lastSubscriber
+	e.notify()
+	return nil
+}
+
+type binaryWriter struct {
+	bytes.Buffer
+}
+
+func (w *binaryWriter) int(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutVarint(buf[:], v)])
+}
+
+func (w *binaryWriter) uint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (w *binaryWriter) float(v float64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	w.Write(buf[:])
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+// size writes the size of a map, distinguishing nil ones.
+func (w *binaryWriter) size(n int, isNil bool) {
+	if isNil {
+		w.uint(0)
+		return
+	}
+	w.uint(uint64(n) + 1)
+}
+
+func (w *binaryWriter) world(world World) {
+	w.size(len(world.Resources), world.Resources == nil)
+	for _, key := range sortedIntKeys(world.Resources) {
+		w.string(key)
+		w.int(int64(world.Resources[key]))
+	}
+	w.size(len(world.Powers), world.Powers == nil)
+	for _, key := range sortedIntKeys(world.Powers) {
+		w.string(key)
+		w.int(int64(world.Powers[key]))
+	}
+
+	w.size(len(world.Reals), world.Reals == nil)
+	for _, key := range sortedFloatKeys(world.Reals) {
+		w.string(key)
+		w.float(world.Reals[key])
+	}
+
+	w.int(int64(world.Turn))
+
+	w.size(len(world.Bounds), world.Bounds == nil)
+	bounds := make([]string, 0, len(world.Bounds))
+	for key := range world.Bounds {
+		bounds = append(bounds, key)
+	}
+	sort.Strings(bounds)
+	for _, key := range bounds {
+		w.string(key)
+		w.int(int64(world.Bounds[key][0]))
+		w.int(int64(world.Bounds[key][1]))
+	}
+
+	w.size(len(world.Derived), world.Derived == nil)
+	for _, key := range sortedStringKeys(world.Derived) {
+		w.string(key)
+		w.string(world.Derived[key])
+	}
+
+	w.size(len(world.NonNegative), world.NonNegative == nil)
+	for _, key := range sortedBoolKeys(world.NonNegative) {
+		w.string(key)
+		if world.NonNegative[key] {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	}
+
+	w.size(len(world.LastChange), world.LastChange == nil)
+	for _, key := range sortedIntKeys(world.LastChange) {
+		w.string(key)
+		w.int(int64(world.LastChange[key]))
+	}
+
+	w.int(int64(world.Rounding))
+	w.size(len(world.RoundingByKey), world.RoundingByKey == nil)
+	rounded := make([]string, 0, len(world.RoundingByKey))
+	for key := range world.RoundingByKey {
+		rounded = append(rounded, key)
+	}
+	sort.Strings(rounded)
+	for _, key := range rounded {
+		w.string(key)
+		w.int(int64(world.RoundingByKey[key]))
+	}
+	if world.StrictBounds {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func (w *binaryWriter) intMap(m map[int]int) {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	w.size(len(keys), m == nil)
+	for _, key := range keys {
+		w.int(int64(key))
+		w.int(int64(m[key]))
+	}
+}
+
+// binaryReader reads what binaryWriter writes. After the first error, it
+// returns zero values and keeps the error in err.
+type binaryReader struct {
+	*bytes.Reader
+	err error
+}
+
+func (r *binaryReader) fail(err error) {
+	if r.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+	}
+}
+
+func (r *binaryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.ReadByte()
+	r.fail(err)
+	return b
+}
+
+func (r *binaryReader) int() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(r)
+	r.fail(err)
+	return v
+}
+
+func (r *binaryReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r)
+	r.fail(err)
+	return v
+}
+
+func (r *binaryReader) float() float64 {
+	var buf [8]byte
+	if r.err != nil {
+		return 0
+	}
+	_, err := io.ReadFull(r, buf[:])
+	r.fail(err)
+	return math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+}
+
+func (r *binaryReader) string() string {
+	n := r.uint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(r.Len()) {
+		r.fail(io.ErrUnexpectedEOF)
+		return ""
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	r.fail(err)
+	return string(buf)
+}
+
+// size reads the size of a map, returning false for nil ones.
+func (r *binaryReader) size() (int, bool) {
+	n := r.uint()
+	if r.err != nil || n == 0 {
+		return 0, false
+	}
+	// Each entry takes at least a byte.
+	if n-1 > uint64(r.Len()) {
+		r.fail(io.ErrUnexpectedEOF)
+		return 0, false
+	}
+	return int(n - 1), true
+}
+
+func (r *binaryReader) ints() map[string]int {
+	n, ok := r.size()
+	if !ok {
+		return nil
+	}
+	m := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		key := r.string()
+		m[key] = int(r.int())
+	}
+	return m
+}
+
+func (r *binaryReader) world() World {
+	var world World
+	world.Resources = r.ints()
+	world.Powers = r.ints()
+	if n, ok := r.size(); ok {
+		world.Reals = make(map[string]float64, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			world.Reals[key] = r.float()
+		}
+	}
+	world.Turn = int(r.int())
+	if n, ok := r.size(); ok {
+		world.Bounds = make(map[string][2]int, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			world.Bounds[key] = [2]int{int(r.int()), int(r.int())}
+		}
+	}
+	if n, ok := r.size(); ok {
+		world.Derived = make(map[string]string, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			world.Derived[key] = r.string()
+		}
+	}
+	if n, ok := r.size(); ok {
+		world.NonNegative = make(map[string]bool, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			world.NonNegative[key] = r.byte() == 1
+		}
+	}
+	world.LastChange = r.ints()
+	world.Rounding = RoundingMode(r.int())
+	if n, ok := r.size(); ok {
+		world.RoundingByKey = make(map[string]RoundingMode, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			world.RoundingByKey[key] = RoundingMode(r.int())
+		}
+	}
+	world.StrictBounds = r.byte() == 1
+	return world
+}
+
+func (r *binaryReader) intMap() map[int]int {
+	n, ok := r.size()
+	if !ok {
+		return nil
+	}
+	m := make(map[int]int, n)
+	for i := 0; i < n; i++ {
+		key := int(r.int())
+		m[key] = int(r.int())
+	}
+	return m
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// richScenario is randomScenario starting in a world using every field
+// that's saved.
+func richScenario(t testing.TB) Scenario {
+	t.Helper()
+	scenario := randomScenario(t)
+	scenario.InitialWorld = &World{
+		Resources:     map[string]int{"Money": 4000, "Food": 10},
+		Powers:        map[string]int{"Military": 90},
+		Reals:         map[string]float64{"Approval": 0.25},
+		Bounds:        map[string][2]int{"Money": {0, 10000}},
+		Derived:       map[string]string{"Popularity": "World.Resources.Money / 100"},
+		NonNegative:   map[string]bool{"Food": true, "Money": false},
+		Rounding:      Floor,
+		RoundingByKey: map[string]RoundingMode{"Food": Ceil},
+	}
+	return scenario
+}
+
+func TestMarshalBinary(t *testing.T) {
+	strict := func(t testing.TB) Scenario {
+		scenario := taxScenario(t)
+		scenario.InitialWorld = &World{Resources: map[string]int{"Money": 4000}, Powers: map[string]int{}, StrictBounds: true}
+		return scenario
+	}
+	tests := []struct {
+		name     string
+		scenario func(t testing.TB) Scenario
+		cfg      GameConfig
+		turns    int
+	}{
+		{"new game", randomScenario, GameConfig{Seed: 3}, 0},
+		{"random game", randomScenario, GameConfig{Seed: 7}, 3},
+		{"rich world", richScenario, GameConfig{Seed: 7}, 2},
+		{"ended game", taxScenario, GameConfig{Seed: 1, MaxTurns: 2}, 2},
+		{"strict bounds", strict, GameConfig{Seed: 1}, 1},
+	}
+	for _, test := range tests {
+		scenario := test.scenario(t)
+		e, err := NewEngine(scenario, test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for turn := 0; turn < test.turns; turn++ {
+			if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+				t.Fatalf("%v: %v", test.name, err)
+			}
+		}
+		data, err := e.SaveState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromJSON, err := LoadState(data, scenario, test.cfg)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		data, err = e.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromBinary, err := NewEngine(scenario, test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fromBinary.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(fromBinary.saved(), fromJSON.saved()) {
+			t.Errorf("%v: got %+v from binary, want %+v as from JSON", test.name, fromBinary.saved(), fromJSON.saved())
+		}
+		if !reflect.DeepEqual(fromBinary.Result(), fromJSON.Result()) {
+			t.Errorf("%v: got result %+v from binary, want %+v", test.name, fromBinary.Result(), fromJSON.Result())
+		}
+		want, got := fromJSON.Decisions(), fromBinary.Decisions()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: got decisions %v, want %v", test.name, descriptions(got), descriptions(want))
+		}
+		if len(want) == 0 {
+			continue
+		}
+		// One more turn must play out the same.
+		if err := fromJSON.Choose(want[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := fromBinary.Choose(got[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(withoutPast(fromBinary.Current()), withoutPast(fromJSON.Current())) {
+			t.Errorf("%v: got world %+v after a turn, want %+v", test.name, fromBinary.Current(), fromJSON.Current())
+		}
+		if !reflect.DeepEqual(fromBinary.Decisions(), fromJSON.Decisions()) {
+			t.Errorf("%v: got decisions %v after a turn, want %v", test.name, descriptions(fromBinary.Decisions()), descriptions(fromJSON.Decisions()))
+		}
+	}
+}
+
+func TestMarshalBinarySize(t *testing.T) {
+	e, err := NewEngine(richScenario(t), GameConfig{Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 0; turn < 5; turn++ {
+		if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jsonData, err := e.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryData, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binaryData) >= len(jsonData)/2 {
+		t.Errorf("got %v bytes, want less than half of %v bytes of JSON", len(binaryData), len(jsonData))
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	e, err := NewEngine(randomScenario(t), GameConfig{Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "invalid saved state: unexpected EOF"},
+		{"unknown version", append([]byte{99}, valid[1:]...), "invalid saved state: unknown version 99"},
+		{"version 0", append([]byte{0}, valid[1:]...), "invalid saved state: unknown version 0"},
+		{"truncated", valid[:len(valid)-1], "invalid saved state: unexpected EOF"},
+		{"trailing data", append(append([]byte{}, valid...), 0), "invalid saved state: trailing data"},
+		{"huge map", []byte{binaryVersion, 0xff, 0xff, 0x03}, "invalid saved state: unexpected EOF"},
+	}
+	for _, test := range tests {
+		err := e.UnmarshalBinary(test.data)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func BenchmarkSaveState(b *testing.B) {
+	e, err := NewEngine(richScenario(b), GameConfig{Seed: 7})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for turn := 0; turn < 5; turn++ {
+		if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	formats := []struct {
+		name      string
+		marshal   func() ([]byte, error)
+		unmarshal func([]byte) error
+	}{
+		{"json", e.SaveState, func(data []byte) error {
+			_, err := LoadState(data, e.scenario, e.cfg)
+			return err
+		}},
+		{"binary", e.MarshalBinary, e.UnmarshalBinary},
+	}
+	for _, format := range formats {
+		data, err := format.marshal()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(format.name+"/marshal", func(b *testing.B) {
+			// SetBytes reports the size of a saved state as throughput.
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := format.marshal(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(format.name+"/unmarshal", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := format.unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
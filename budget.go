@@ -0,0 +1,24 @@
+package main
+
+// CanAfford reports whether the choice can be made without overspending
+// the budget resource configured in GameConfig.BudgetResource. Random
+// deltas are assumed to take their expected value.
+func (e *Engine) CanAfford(c Choice) bool {
+	return affordable(e.world, c, e.cfg.BudgetResource)
+}
+
+// affordable reports whether applying choice to world leaves resource,
+// fractional or not, non-negative. A choice that doesn't lower the resource is always
+// affordable, even in debt, and so is any choice if resource is empty.
+func affordable(world World, choice Choice, resource string) bool {
+	if resource == "" {
+		return true
+	}
+	next, _, err := world.ApplyPreview(choice)
+	if err != nil {
+		// Let Apply report the error.
+		return true
+	}
+	after := next.value(resource)
+	return after >= 0 || after >= world.value(resource)
+}
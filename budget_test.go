@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestAffordable(t *testing.T) {
+	spend := func(resource string, amount float64) Choice {
+		return Choice{Description: "Spend", Change: Change{Resources: map[string]Delta{resource: {-amount, 0, float64(OpAdd)}}}}
+	}
+	cost := func(money float64) Choice {
+		return spend("Money", money)
+	}
+	tests := []struct {
+		name     string
+		money    int
+		choice   Choice
+		resource string
+		want     bool
+	}{
+		{"fractional within budget", 100, spend("Approval", 0.25), "Approval", true},
+		{"fractional overspending", 100, spend("Approval", 0.75), "Approval", false},
+		{"within budget", 100, cost(50), "Money", true},
+		{"whole budget", 100, cost(100), "Money", true},
+		{"overspending", 100, cost(101), "Money", false},
+		{"gain", 100, cost(-50), "Money", true},
+		{"other resource", 100, Choice{Change: Change{Resources: map[string]Delta{"Gold": {-500, 0, float64(OpAdd)}}}}, "Money", true},
+		{"deeper in debt", -100, cost(10), "Money", false},
+		{"out of debt", -100, cost(-10), "Money", true},
+		{"no budget", 100, cost(1000), "", true},
+	}
+	for _, test := range tests {
+		world := World{Resources: map[string]int{"Money": test.money}, Powers: map[string]int{}, Reals: map[string]float64{"Approval": 0.5}}
+		if got := affordable(world, test.choice, test.resource); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEngineBudget(t *testing.T) {
+	splurge := Decision{Description: "Splurge", Choices: []Choice{
+		{Description: "Palace", Change: Change{Resources: map[string]Delta{"Money": {-5000, 0, float64(OpAdd)}}}},
+		{Description: "Statue", Change: Change{Resources: map[string]Delta{"Money": {-3000, 0, float64(OpAdd)}}}},
+	}}
+	scenario := Scenario{Rules: []Rule{mustRule(t, "splurge", "true", 1, splurge)}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, BudgetResource: "Money"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		choice int
+		ok     bool
+		money  int
+	}{
+		{0, false, 4000},
+		{1, true, 1000},
+		{1, false, 1000},
+	}
+	for _, test := range tests {
+		choice := e.Decisions()[0].Choices[test.choice]
+		if got := e.CanAfford(choice); got != test.ok {
+			t.Errorf("%v with Money %v: got CanAfford %v, want %v", choice.Description, e.Current().Resources["Money"], got, test.ok)
+		}
+		err := e.Choose(choice)
+		if (err == nil) != test.ok {
+			t.Errorf("%v: got error %v, want success %v", choice.Description, err, test.ok)
+		}
+		if got := e.Current().Resources["Money"]; got != test.money {
+			t.Errorf("%v: got Money %v, want %v", choice.Description, got, test.money)
+		}
+	}
+}
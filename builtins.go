@@ -0,0 +1,110 @@
+package main
+
+import "strings"
+
+// builtinFuncs are the names of the functions available to every guard.
+var builtinFuncs = []string{"history", "sumPowers", "avgResources", "fired", "choseChoice", "res", "pow"}
+
+// builtins returns the functions available to every guard, evaluated
+// against *w.
+func builtins(w *World) map[string]interface{} {
+	return map[string]interface{}{
+		"history":      historyFunc(func(key string, n float64) float64 { return w.history(key, n) }),
+		"sumPowers":    sumPowersFunc(func(prefix string) float64 { return w.sumPowers(prefix) }),
+		"avgResources": avgResourcesFunc(func() float64 { return w.avgResources() }),
+		"fired":        firedFunc(func(rule string) bool { return w.fired(rule) }),
+		"choseChoice":  choseChoiceFunc(func(rule, choice string) bool { return w.choseChoice(rule, choice) }),
+		"res":          valueFunc(func(key string, def float64) float64 { return w.res(key, def) }),
+		"pow":          valueFunc(func(key string, def float64) float64 { return w.pow(key, def) }),
+	}
+}
+
+// sumPowersFunc is the type of the sumPowers guard function:
+// sumPowers(prefix) is the total of the powers whose key starts with prefix,
+// e.g. sumPowers("") totals all powers.
+type sumPowersFunc func(prefix string) float64
+
+func (w World) sumPowers(prefix string) float64 {
+	sum := 0
+	for key, value := range w.Powers {
+		if strings.HasPrefix(key, prefix) {
+			sum += value
+		}
+	}
+	return float64(sum)
+}
+
+// avgResourcesFunc is the type of the avgResources guard function:
+// avgResources() is the average of all resources, fractional ones included,
+// or 0 if there are none.
+type avgResourcesFunc func() float64
+
+func (w World) avgResources() float64 {
+	n := len(w.Resources) + len(w.Reals)
+	if n == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range w.Resources {
+		sum += float64(value)
+	}
+	for _, value := range w.Reals {
+		sum += value
+	}
+	return sum / float64(n)
+}
+
+// firedFunc is the type of the fired guard function: fired(rule) reports
+// whether a decision of the named rule was chosen earlier in the game.
+type firedFunc func(rule string) bool
+
+func (w World) fired(rule string) bool {
+	if w.state == nil {
+		return false
+	}
+	for _, chosen := range w.state.Chosen {
+		if chosen.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// choseChoiceFunc is the type of the choseChoice guard function:
+// choseChoice(rule, choice) reports whether the choice with the given
+// description was made in a decision of the named rule earlier in the game.
+type choseChoiceFunc func(rule, choice string) bool
+
+func (w World) choseChoice(rule, choice string) bool {
+	if w.state == nil {
+		return false
+	}
+	for _, chosen := range w.state.Chosen {
+		if chosen.Rule == rule && chosen.Choice == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// valueFunc is the type of the res and pow guard functions: res(key, def)
+// is the value of the resource key, fractional or not, or def if there's no
+// such resource, and pow(key, def) is the same for powers.
+type valueFunc func(key string, def float64) float64
+
+func (w World) res(key string, def float64) float64 {
+	if v, ok := w.Resources[key]; ok {
+		return float64(v)
+	}
+	if v, ok := w.Reals[key]; ok {
+		return v
+	}
+	return def
+}
+
+func (w World) pow(key string, def float64) float64 {
+	if v, ok := w.Powers[key]; ok {
+		return float64(v)
+	}
+	return def
+}
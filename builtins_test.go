@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 3000, "Food": 1000},
+		Reals:     map[string]float64{"Approval": 50},
+		Powers:    map[string]int{"MilitaryArmy": 120, "MilitaryNavy": 90, "Legislation": 10},
+	}
+	tests := []struct {
+		guard string
+		world World
+		want  bool
+	}{
+		{`sumPowers("Military") > 200`, world, true},
+		{`sumPowers("Military") == 210`, world, true},
+		{`sumPowers("") == 220`, world, true},
+		{`sumPowers("Clergy") == 0`, world, true},
+		{`avgResources() > 1349 and avgResources() < 1351`, world, true},
+		{`avgResources() == 0`, World{}, true},
+		{`sumPowers("") == 0`, World{}, true},
+		{`res("Popularity", 0) > 50`, world, false},
+		{`res("Popularity", 60) > 50`, world, true},
+		{`res("Money", 0) == 3000`, world, true},
+		{`res("Approval", 0) == 50`, world, true},
+		{`res("Legislation", -1) == -1`, world, true},
+		{`pow("Legislation", 0) == 10`, world, true},
+		{`pow("Money", 7) == 7`, world, true},
+		{`pow("Clergy", 0) == 0`, World{}, true},
+	}
+	for _, test := range tests {
+		guard, err := NewGuard(test.guard)
+		if err != nil {
+			t.Fatalf("%v: %v", test.guard, err)
+		}
+		pass, err := guard.Pass(test.world)
+		if err != nil || pass != test.want {
+			t.Errorf("%v: got %v, %v, want %v", test.guard, pass, err, test.want)
+		}
+	}
+}
+
+func TestFiredBuiltins(t *testing.T) {
+	state := NewRuleState()
+	state.Chosen = []ChosenChoice{{Rule: "putsch", Choice: "Accept"}, {Choice: "Pass turn"}}
+	tests := []struct {
+		guard string
+		state *RuleState
+		want  bool
+	}{
+		{`fired("putsch")`, &state, true},
+		{`fired("coup")`, &state, false},
+		{`choseChoice("putsch", "Accept")`, &state, true},
+		{`choseChoice("putsch", "Reject")`, &state, false},
+		{`choseChoice("coup", "Accept")`, &state, false},
+		{`fired("putsch")`, nil, false},
+		{`choseChoice("putsch", "Accept")`, nil, false},
+	}
+	for _, test := range tests {
+		guard, err := NewGuard(test.guard)
+		if err != nil {
+			t.Fatalf("%v: %v", test.guard, err)
+		}
+		pass, err := guard.Pass(World{state: test.state})
+		if err != nil || pass != test.want {
+			t.Errorf("%v: got %v, %v, want %v", test.guard, pass, err, test.want)
+		}
+	}
+}
+
+func TestFiredGuard(t *testing.T) {
+	putsch := Decision{Description: "Make putsch", Choices: []Choice{{Description: "Accept"}, {Description: "Reject"}}}
+	consolidate := Decision{Description: "Consolidate power", Choices: []Choice{{Description: "Purge"}}}
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "putsch", "true", 1, putsch),
+		mustRule(t, "consolidate", `choseChoice("putsch", "Accept")`, 1, consolidate),
+		mustRule(t, "purge", `fired("consolidate")`, 1, Decision{Description: "Purge again", Choices: []Choice{{Description: "Purge"}}}),
+	}}
+	tests := []struct {
+		choices []string
+		want    string
+	}{
+		{nil, "[Make putsch]"},
+		{[]string{"Reject"}, "[Make putsch]"},
+		{[]string{"Accept"}, "[Consolidate power Make putsch]"},
+		{[]string{"Reject", "Accept", "Purge"}, "[Consolidate power Purge again Make putsch]"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, choice := range test.choices {
+			mustChoose(t, e, choice)
+		}
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != test.want {
+			t.Errorf("%v: got %v, want %v", test.choices, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+// options are the command-line options.
+type options struct {
+	// ScenarioPath is the scenario file to play. If empty, the default
+	// scenario is played.
+	ScenarioPath string
+	Seed         int64
+	// UI is "console", "web" or "protocol".
+	UI string
+	// Addr is the address the web UI listens on.
+	Addr string
+}
+
+func parseFlags(args []string) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("politika", flag.ContinueOnError)
+	fs.StringVar(&opts.ScenarioPath, "scenario", "", "scenario `file` to play (.json, .yaml, .toml or .pol)")
+	fs.Int64Var(&opts.Seed, "seed", 0, "random seed; the current time if 0")
+	fs.StringVar(&opts.UI, "ui", "console", "user interface: console, web or protocol")
+	fs.StringVar(&opts.Addr, "addr", "localhost:8080", "`address` the web UI listens on")
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	switch opts.UI {
+	case "console", "web", "protocol":
+	default:
+		err := fmt.Errorf("unknown UI %q", opts.UI)
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return options{}, err
+	}
+	return opts, nil
+}
+
+// scenario loads the scenario to play and validates it.
+func (o options) scenario() (Scenario, error) {
+	if o.ScenarioPath == "" {
+		scenario, err := defaultScenario()
+		if err != nil {
+			return Scenario{}, err
+		}
+		return scenario, scenario.Validate()
+	}
+	return loadScenarioPath(o.ScenarioPath)
+}
+
+// loadScenarioPath loads a scenario with the loader matching the file's
+// extension, defaulting to JSON.
+func loadScenarioPath(path string) (Scenario, error) {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return LoadScenarioYAML(path)
+	case ".toml":
+		return LoadScenarioTOML(path)
+	case ".pol":
+		return loadScenarioDSL(path)
+	default:
+		return LoadScenario(path)
+	}
+}
+
+// gameConfig returns the configuration of the game to play. The default
+// scenario is lost once out of Money, which it budgets; scenario files
+// have no such conditions.
+func (o options) gameConfig() GameConfig {
+	cfg := GameConfig{
+		FallbackDecision: &Decision{
+			Description: "Pass turn",
+			Choices: []Choice{
+				{
+					Description: "Accept",
+				},
+			}},
+		Seed: o.Seed,
+	}
+	if o.ScenarioPath == "" {
+		cfg.LoseConditions = []string{"World.Resources.Money <= 0"}
+		cfg.BudgetResource = "Money"
+	}
+	return cfg
+}
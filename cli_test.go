@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want options
+		err  bool
+	}{
+		{
+			args: nil,
+			want: options{UI: "console", Addr: "localhost:8080"},
+		},
+		{
+			args: []string{"-scenario", "game.json", "-seed", "42", "-ui", "console"},
+			want: options{ScenarioPath: "game.json", Seed: 42, UI: "console", Addr: "localhost:8080"},
+		},
+		{
+			args: []string{"-ui", "web", "-addr", ":9000"},
+			want: options{UI: "web", Addr: ":9000"},
+		},
+		{
+			args: []string{"-ui=protocol", "-seed=-3"},
+			want: options{Seed: -3, UI: "protocol", Addr: "localhost:8080"},
+		},
+		{args: []string{"-ui", "gui"}, err: true},
+		{args: []string{"-seed", "many"}, err: true},
+		{args: []string{"-level", "2"}, err: true},
+	}
+	for _, test := range tests {
+		got, err := parseFlags(test.args)
+		if (err != nil) != test.err {
+			t.Errorf("%v: got error %v, want error %v", test.args, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%v: got %+v, want %+v", test.args, got, test.want)
+		}
+	}
+}
+
+func TestOptionsScenario(t *testing.T) {
+	tests := []struct {
+		path  string
+		rules int
+		err   bool
+	}{
+		{"", 2, false},
+		{"scenarios/simple.json", 2, false},
+		{"scenarios/simple.yaml", 2, false},
+		{"scenarios/simple.toml", 2, false},
+		{"scenarios/simple.pol", 2, false},
+		{"scenarios/missing.json", 0, true},
+	}
+	for _, test := range tests {
+		scenario, err := options{ScenarioPath: test.path}.scenario()
+		if (err != nil) != test.err {
+			t.Errorf("%q: got error %v, want error %v", test.path, err, test.err)
+			continue
+		}
+		if len(scenario.Rules) != test.rules {
+			t.Errorf("%q: got %v rules, want %v", test.path, len(scenario.Rules), test.rules)
+		}
+	}
+}
+
+func TestOptionsGameConfig(t *testing.T) {
+	tests := []struct {
+		opts   options
+		seed   int64
+		lose   string
+		budget string
+	}{
+		{options{}, 0, "[World.Resources.Money <= 0]", "Money"},
+		{options{Seed: 42}, 42, "[World.Resources.Money <= 0]", "Money"},
+		{options{ScenarioPath: "scenarios/simple.json", Seed: 7}, 7, "[]", ""},
+	}
+	for _, test := range tests {
+		cfg := test.opts.gameConfig()
+		if cfg.Seed != test.seed {
+			t.Errorf("%+v: got seed %v, want %v", test.opts, cfg.Seed, test.seed)
+		}
+		if cfg.FallbackDecision == nil || len(cfg.FallbackDecision.Choices) == 0 {
+			t.Errorf("%+v: got fallback decision %+v, want one with a choice", test.opts, cfg.FallbackDecision)
+		}
+		if got := fmt.Sprint(cfg.LoseConditions); got != test.lose || cfg.BudgetResource != test.budget {
+			t.Errorf("%+v: got lose conditions %v and budget %q, want %v and %q", test.opts, got, cfg.BudgetResource, test.lose, test.budget)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import "time"
+
+// Clock tells the time for real-time features: time-based scheduled events
+// and expiring decisions.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, telling the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// MockClock is a Clock whose time only changes when it's advanced, e.g. to
+// test real-time scenarios.
+type MockClock struct {
+	now time.Time
+}
+
+// NewMockClock returns a clock stopped at now.
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *MockClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// timeSpan is the time elapsed since the game started at the start of the
+// previous turn and at the start of the current one.
+type timeSpan struct {
+	from, to time.Duration
+}
+
+func (s timeSpan) contains(d time.Duration) bool {
+	return s.from < d && d <= s.to
+}
+
+// expired reports whether decision was offered longer than its Expires ago.
+func (e *Engine) expired(decision Decision) bool {
+	return decision.Expires > 0 && e.clock.Now().Sub(e.offeredAt) >= decision.Expires
+}
+
+// Tick withdraws the offered decisions that expired, applying their
+// defaults. Once none is left, the turn ends without a choice, as if it was
+// skipped. Real-time UIs should call it regularly, e.g. every second.
+func (e *Engine) Tick() error {
+	defer e.enter()()
+	var offered, expired []Decision
+	for _, decision := range e.decisions {
+		if e.expired(decision) {
+			expired = append(expired, decision)
+		} else {
+			offered = append(offered, decision)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	undo := e.snapshot()
+	restore := e.checkpoint()
+	fail := func(err error) error {
+		restore()
+		return err
+	}
+	e.push(undo)
+	if len(offered) == 0 && e.chain == 0 {
+		e.past.push(e.world.Copy())
+	}
+	for _, decision := range expired {
+		e.settle(decision)
+	}
+	e.decisions = locate(offered)
+	if _, err := e.expire(expired); err != nil {
+		return fail(err)
+	}
+	if len(offered) > 0 {
+		return nil
+	}
+	if err := e.endTurn(); err != nil {
+		return fail(err)
+	}
+	if err := e.conclude(nil); err != nil {
+		return fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeBasedScheduledEvent(t *testing.T) {
+	accept := []Choice{{Description: "Accept"}}
+	scenario := Scenario{
+		Rules: []Rule{mustRule(t, "tax", "true", 1, Decision{Description: "Tax", Choices: accept})},
+		Scheduled: []ScheduledEvent{
+			{After: time.Minute, Decision: Decision{Description: "Invasion", Choices: accept}},
+		},
+	}
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{30 * time.Second, "[Tax]"},
+		{29 * time.Second, "[Tax]"},
+		// The minute passes during this turn, so the event is offered next.
+		{2 * time.Second, "[Invasion Tax]"},
+		{time.Minute, "[Tax]"},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		mustChoose(t, e, "Accept")
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != test.want {
+			t.Errorf("turn %v: got %v, want %v", i+1, got, test.want)
+		}
+	}
+}
+
+func TestExpiringDecisions(t *testing.T) {
+	choices := func(descriptions ...string) []Choice {
+		c := make([]Choice, len(descriptions))
+		for i, description := range descriptions {
+			c[i] = Choice{Description: description}
+		}
+		return c
+	}
+	ultimatum := mustRule(t, "ultimatum", "true", 1, Decision{Description: "Ultimatum", Choices: choices("Yield", "Resist"), Expires: 30 * time.Second})
+	tax := mustRule(t, "tax", "true", 1, Decision{Description: "Tax", Choices: choices("Raise"), Expires: time.Minute})
+	scenario := Scenario{Rules: []Rule{ultimatum, tax}}
+
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expired []string
+	e.OnEvent(func(event Event) {
+		if event.Kind == DecisionsExpired {
+			expired = append(expired, fmt.Sprint(descriptions(event.Decisions)))
+		}
+	})
+
+	steps := []struct {
+		advance   time.Duration
+		turn      int
+		decisions string
+	}{
+		{10 * time.Second, 0, "[Tax Ultimatum]"},
+		{20 * time.Second, 0, "[Tax]"},
+		{30 * time.Second, 1, "[Tax Ultimatum]"},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		if err := e.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Current().Turn; got != step.turn {
+			t.Errorf("step %v: got turn %v, want %v", i, got, step.turn)
+		}
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != step.decisions {
+			t.Errorf("step %v: got decisions %v, want %v", i, got, step.decisions)
+		}
+	}
+	if want := "[[Ultimatum] [Tax]]"; fmt.Sprint(expired) != want {
+		t.Errorf("got expired %v, want %v", expired, want)
+	}
+
+	// Choosing an expired decision fails even before Tick.
+	clock.Advance(45 * time.Second)
+	err = e.Choose(findChoice(t, e.Decisions(), "Yield"))
+	if err == nil || err.Error() != "decision Ultimatum expired" {
+		t.Errorf("got error %v, want decision Ultimatum expired", err)
+	}
+	mustChoose(t, e, "Raise")
+}
+
+func TestExpiringDefaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		war   int
+		err   string
+		money int
+		// decisions are those offered after Tick.
+		decisions string
+		// undone are the decisions offered after Undo, or its error.
+		undone string
+	}{
+		{"applied", -500, "", 3500, "[Tax]", "[Tax Ultimatum]"},
+		{"failed", -5000, "below its lower bound", 4000, "[Tax Ultimatum]", "nothing to undo"},
+	}
+	for _, test := range tests {
+		ultimatum := Decision{
+			Description: "Ultimatum",
+			Choices:     []Choice{{Description: "Yield"}},
+			Expires:     30 * time.Second,
+			Default:     &Choice{Description: "War", Change: Change{Resources: map[string]Delta{"Money": {float64(test.war), 0, float64(OpAdd)}}}},
+		}
+		scenario := taxScenario(t)
+		scenario.Rules[0].Decision.Expires = time.Minute
+		scenario.Rules = append(scenario.Rules, mustRule(t, "ultimatum", "true", 1, ultimatum))
+		scenario.InitialWorld = &World{
+			Resources:    map[string]int{"Money": 4000},
+			Powers:       map[string]int{},
+			Bounds:       map[string][2]int{"Money": {0, 10000}},
+			StrictBounds: true,
+		}
+		clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		e, err := NewEngine(scenario, GameConfig{Seed: 1, Clock: clock, MaxUndo: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(30 * time.Second)
+		err = e.Tick()
+		if got := fmt.Sprint(err); test.err != "" && !strings.Contains(got, test.err) || test.err == "" && err != nil {
+			t.Errorf("%v: got error %v, want %q", test.name, err, test.err)
+		}
+		if got := e.Current().Resources["Money"]; got != test.money {
+			t.Errorf("%v: got Money %v, want %v", test.name, got, test.money)
+		}
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != test.decisions {
+			t.Errorf("%v: got decisions %v, want %v", test.name, got, test.decisions)
+		}
+		var undone string
+		if err := e.Undo(); err != nil {
+			undone = err.Error()
+		} else {
+			undone = fmt.Sprint(descriptions(e.Decisions()))
+		}
+		if undone != test.undone {
+			t.Errorf("%v: got %v after Undo, want %v", test.name, undone, test.undone)
+		}
+		if got := e.Current().Resources["Money"]; got != 4000 {
+			t.Errorf("%v: got Money %v after Undo, want 4000", test.name, got)
+		}
+	}
+}
+
+func TestValidateScheduledTime(t *testing.T) {
+	scenario := Scenario{Scheduled: []ScheduledEvent{
+		{After: -time.Second, Decision: Decision{Choices: []Choice{{Description: "Accept"}}}},
+	}}
+	err := scenario.Validate()
+	if want := "scheduled event 0: negative time -1s"; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+// decay applies the deltas of decay, e.g. {"Popularity": {0.95, 0}}, to the
+// resources, fractional resources and powers of w at the end of a turn,
+// respecting their bounds. Keys missing from the world are left alone. Keys
+// are decayed in sorted order so that random deltas are reproducible.
+func (w *World) decay(decay map[string]Delta, r Rand) error {
+	if len(decay) == 0 {
+		return nil
+	}
+	for _, key := range sortedDeltaKeys(decay) {
+		delta := decay[key]
+		if value, ok := w.Reals[key]; ok {
+			w.Reals[key] = w.clampReal(key, delta.apply(value, r))
+		} else if value, ok := w.Resources[key]; ok {
+			w.Resources[key] = w.clamp(key, updatedValue(value, delta, r, w.rounding(key)))
+		} else if value, ok := w.Powers[key]; ok {
+			w.Powers[key] = w.clamp(key, updatedValue(value, delta, r, w.rounding(key)))
+		}
+	}
+	return w.updateDerived()
+}
+
+// grow compounds the resources, fractional resources and powers of w at the
+// end of a turn by their rate in growth, e.g. {"Money": 0.05} for 5%
+// interest, like decay does.
+func (w *World) grow(growth map[string]float64, r Rand) error {
+	if len(growth) == 0 {
+		return nil
+	}
+	deltas := make(map[string]Delta, len(growth))
+	for key, rate := range growth {
+		deltas[key] = Delta{1 + rate, 0}
+	}
+	return w.decay(deltas, r)
+}
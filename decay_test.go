@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecay(t *testing.T) {
+	tests := []struct {
+		name  string
+		decay map[string]Delta
+		want  World
+	}{
+		{
+			name:  "none",
+			decay: nil,
+			want:  World{Resources: map[string]int{"Money": 1000, "Food": 5}, Powers: map[string]int{"Military": 90}, Reals: map[string]float64{"Popularity": 50}},
+		},
+		{
+			name:  "resource and power",
+			decay: map[string]Delta{"Money": {0.9, 0}, "Military": {1, -10}},
+			want:  World{Resources: map[string]int{"Money": 900, "Food": 5}, Powers: map[string]int{"Military": 80}, Reals: map[string]float64{"Popularity": 50}},
+		},
+		{
+			name:  "fractional resource",
+			decay: map[string]Delta{"Popularity": {0.95, 0}},
+			want:  World{Resources: map[string]int{"Money": 1000, "Food": 5}, Powers: map[string]int{"Military": 90}, Reals: map[string]float64{"Popularity": 47.5}},
+		},
+		{
+			name:  "bounds",
+			decay: map[string]Delta{"Money": {0, 0}, "Military": {1, 100}},
+			want:  World{Resources: map[string]int{"Money": 500, "Food": 5}, Powers: map[string]int{"Military": 100}, Reals: map[string]float64{"Popularity": 50}},
+		},
+		{
+			name:  "non-negative",
+			decay: map[string]Delta{"Food": {1, -10}},
+			want:  World{Resources: map[string]int{"Money": 1000, "Food": 0}, Powers: map[string]int{"Military": 90}, Reals: map[string]float64{"Popularity": 50}},
+		},
+		{
+			name:  "missing key",
+			decay: map[string]Delta{"Gold": {0.5, 0}},
+			want:  World{Resources: map[string]int{"Money": 1000, "Food": 5}, Powers: map[string]int{"Military": 90}, Reals: map[string]float64{"Popularity": 50}},
+		},
+	}
+	for _, test := range tests {
+		world := World{
+			Resources:   map[string]int{"Money": 1000, "Food": 5},
+			Powers:      map[string]int{"Military": 90},
+			Reals:       map[string]float64{"Popularity": 50},
+			Bounds:      map[string][2]int{"Money": {500, 5000}, "Military": {0, 100}},
+			NonNegative: map[string]bool{"Food": true},
+		}
+		if err := world.decay(test.decay, fixedRand(0)); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		got := World{Resources: world.Resources, Powers: world.Powers, Reals: world.Reals}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEngineDecay(t *testing.T) {
+	pass := Decision{Description: "Pass", Choices: []Choice{{Description: "Wait"}}}
+	scenario := Scenario{
+		Rules: []Rule{mustRule(t, "pass", "true", 1, pass)},
+		Decay: map[string]Delta{"Money": {1, -100}, "Military": {0.5, 0}},
+	}
+	tests := []struct {
+		money    int
+		military int
+	}{
+		{3900, 45},
+		{3800, 23},
+		{3700, 12},
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn, test := range tests {
+		mustChoose(t, e, "Wait")
+		world := e.Current()
+		if world.Resources["Money"] != test.money || world.Powers["Military"] != test.military {
+			t.Errorf("turn %v: got Money %v and Military %v, want %v and %v", turn, world.Resources["Money"], world.Powers["Military"], test.money, test.military)
+		}
+	}
+}
+
+func TestEngineGrowth(t *testing.T) {
+	pass := Decision{Description: "Pass", Choices: []Choice{{Description: "Wait"}}}
+	tests := []struct {
+		name   string
+		growth map[string]float64
+		decay  map[string]Delta
+		bounds map[string][2]int
+		money  []int
+	}{
+		{"none", nil, nil, nil, []int{4000, 4000, 4000}},
+		{"interest", map[string]float64{"Money": 0.05}, nil, nil, []int{4200, 4410, 4631}},
+		{"shrinking", map[string]float64{"Money": -0.5}, nil, nil, []int{2000, 1000, 500}},
+		{"bounded", map[string]float64{"Money": 0.05}, nil, map[string][2]int{"Money": {0, 4300}}, []int{4200, 4300, 4300}},
+		{"then decay", map[string]float64{"Money": 0.1}, map[string]Delta{"Money": {1, -400}}, nil, []int{4000, 4000, 4000}},
+	}
+	for _, test := range tests {
+		scenario := Scenario{Rules: []Rule{mustRule(t, "pass", "true", 1, pass)}, Decay: test.decay}
+		if test.bounds != nil {
+			initial := scenario.initialWorld().WithBounds(test.bounds)
+			scenario.InitialWorld = &initial
+		}
+		e, err := NewEngine(scenario, GameConfig{Seed: 1, Growth: test.growth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for turn, money := range test.money {
+			mustChoose(t, e, "Wait")
+			if got := e.Current().Resources["Money"]; got != money {
+				t.Errorf("%v, turn %v: got Money %v, want %v", test.name, turn, got, money)
+			}
+		}
+	}
+}
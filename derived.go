@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/antonmedv/expr"
+)
+
+var resourceRef = regexp.MustCompile(`World\.Resources(?:\.(\w+)|\["([^"]+)"\])`)
+
+// compiledDerived are the expressions of derived resources, compiled once
+// and sorted in the order they're evaluated in.
+type compiledDerived struct {
+	names []string
+	nodes []expr.Node
+}
+
+// compileDerived compiles the expressions of the derived resources unless
+// they already are.
+func (w *World) compileDerived() error {
+	if w.derived != nil || len(w.Derived) == 0 {
+		return nil
+	}
+	order, err := derivedOrder(w.Derived)
+	if err != nil {
+		return err
+	}
+	compiled := &compiledDerived{names: order, nodes: make([]expr.Node, len(order))}
+	for i, name := range order {
+		compiled.nodes[i], err = expr.Parse(w.Derived[name], expr.Define("World", World{}))
+		if err != nil {
+			return fmt.Errorf("derived resource %v: %v", name, err)
+		}
+	}
+	w.derived = compiled
+	return nil
+}
+
+// updateDerived recomputes the derived resources, evaluating each one after
+// the derived resources it depends on.
+func (w *World) updateDerived() error {
+	if err := w.compileDerived(); err != nil {
+		return err
+	}
+	if w.derived == nil {
+		return nil
+	}
+	for i, name := range w.derived.names {
+		value, err := evalNumber(w.derived.nodes[i], *w)
+		if err != nil {
+			return fmt.Errorf("derived resource %v: %v", name, err)
+		}
+		w.Resources[name] = w.clamp(name, w.rounding(name).round(value))
+	}
+	return nil
+}
+
+// NumberExpr is a compiled expression evaluating to a number, e.g.
+// "1 - World.Resources.Money / 10000".
+type NumberExpr struct {
+	expr.Node
+	// Source is the expression the node was parsed from.
+	Source string
+}
+
+func NewNumberExpr(source string) (*NumberExpr, error) {
+	node, err := expr.Parse(source, expr.Define("World", World{}))
+	if err != nil {
+		return nil, err
+	}
+	return &NumberExpr{Node: node, Source: source}, nil
+}
+
+// Eval evaluates the expression against world.
+func (e *NumberExpr) Eval(world World) (float64, error) {
+	return evalNumber(e.Node, world)
+}
+
+// evalNumber evaluates a numeric expression against world.
+func evalNumber(node expr.Node, world World) (float64, error) {
+	out, err := expr.Run(node, map[string]World{"World": world})
+	if err != nil {
+		return 0, err
+	}
+	switch v := out.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", out)
+	}
+}
+
+// derivedOrder sorts derived resources so that each comes after the ones
+// it references, or fails if they reference each other in a cycle.
+func derivedOrder(derived map[string]string) ([]string, error) {
+	names := sortedStringKeys(derived)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	marks := make(map[string]int, len(derived))
+	order := make([]string, 0, len(derived))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("derived resources form a cycle: %v", append(path, name))
+		case done:
+			return nil
+		}
+		marks[name] = visiting
+		for _, m := range resourceRef.FindAllStringSubmatch(derived[name], -1) {
+			dep := m[1] + m[2]
+			if _, ok := derived[dep]; ok {
+				if err := visit(dep, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		marks[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDerivedResources(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 1000},
+		Powers:    map[string]int{"Legislation": 10},
+		Derived: map[string]string{
+			// Stability depends on Popularity, which must be computed first.
+			"Stability":  "World.Resources.Popularity + World.Powers.Legislation",
+			"Popularity": "World.Resources.Money / 100",
+		},
+	}
+	tests := []struct {
+		change     Change
+		popularity int
+		stability  int
+	}{
+		{Change{}, 10, 20},
+		{Change{Resources: map[string]Delta{"Money": {2, 0}}}, 20, 30},
+		{Change{Powers: map[string]Delta{"Legislation": {0, 50}}}, 20, 70},
+	}
+	for i, test := range tests {
+		if err := world.Apply(Choice{Change: test.change}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := world.Resources["Popularity"]; got != test.popularity {
+			t.Errorf("change %d: got Popularity %v, want %v", i, got, test.popularity)
+		}
+		if got := world.Resources["Stability"]; got != test.stability {
+			t.Errorf("change %d: got Stability %v, want %v", i, got, test.stability)
+		}
+	}
+}
+
+func TestDerivedResourcesInitially(t *testing.T) {
+	tests := []struct {
+		money      int
+		popularity int
+		decisions  string
+	}{
+		{1000, 10, "[Celebrate]"},
+		{500, 5, "[]"},
+	}
+	for _, test := range tests {
+		scenario := Scenario{
+			Rules: []Rule{mustRule(t, "celebrate", "World.Resources.Popularity >= 10", 1, Decision{
+				Description: "Celebrate",
+				Choices:     []Choice{{Description: "Accept"}},
+			})},
+			InitialWorld: &World{
+				Resources: map[string]int{"Money": test.money},
+				Derived:   map[string]string{"Popularity": "World.Resources.Money / 100"},
+			},
+		}
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Current().Resources["Popularity"]; got != test.popularity {
+			t.Errorf("Money %v: got Popularity %v, want %v", test.money, got, test.popularity)
+		}
+		if world, ok := e.SnapshotAt(0); !ok || world.Resources["Popularity"] != test.popularity {
+			t.Errorf("Money %v: got snapshot of turn 0 with Popularity %v, want %v", test.money, world.Resources["Popularity"], test.popularity)
+		}
+		if got := fmt.Sprint(descriptions(e.Decisions())); got != test.decisions {
+			t.Errorf("Money %v: got decisions %v, want %v", test.money, got, test.decisions)
+		}
+		if got := fmt.Sprint(scenario.Summary().Likely); got != test.decisions {
+			t.Errorf("Money %v: got likely decisions %v, want %v", test.money, got, test.decisions)
+		}
+	}
+}
+
+func TestDerivedOrder(t *testing.T) {
+	tests := []struct {
+		derived map[string]string
+		want    string
+		err     bool
+	}{
+		{
+			derived: map[string]string{"A": "1", "B": "2"},
+			want:    "[A B]",
+		},
+		{
+			derived: map[string]string{"A": `World.Resources["B"] + 1`, "B": "World.Resources.C", "C": "3"},
+			want:    "[C B A]",
+		},
+		{
+			derived: map[string]string{"A": "World.Resources.B", "B": "World.Resources.A"},
+			err:     true,
+		},
+		{
+			derived: map[string]string{"A": "World.Resources.A + 1"},
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		order, err := derivedOrder(test.derived)
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: got order %v, want a cycle", test.derived, order)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(order) != test.want {
+			t.Errorf("%v: got %v, %v, want %v", test.derived, order, err, test.want)
+		}
+	}
+}
+
+func TestDerivedCompiledOnce(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 1000},
+		Powers:    map[string]int{},
+		Derived:   map[string]string{"Popularity": "World.Resources.Money / 100"},
+	}
+	if err := world.Apply(Choice{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	compiled := world.derived
+	if compiled == nil || fmt.Sprint(compiled.names) != "[Popularity]" {
+		t.Fatalf("got compiled derived resources %+v, want Popularity", compiled)
+	}
+	copy := world.Copy()
+	for _, w := range []*World{&world, &copy} {
+		if err := w.Apply(Choice{Change: Change{Resources: map[string]Delta{"Money": {2, 0}}}}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if w.derived != compiled {
+			t.Errorf("got the derived resources compiled again")
+		}
+		if got := w.Resources["Popularity"]; got != 20 {
+			t.Errorf("got Popularity %v, want 20", got)
+		}
+	}
+}
+
+func TestDerivedCompileErrors(t *testing.T) {
+	tests := []struct {
+		derived map[string]string
+		err     string
+	}{
+		{map[string]string{"Popularity": "World.Resources.Money /"}, "derived resource Popularity: "},
+		{map[string]string{"A": "World.Resources.B", "B": "World.Resources.A"}, "derived resources form a cycle"},
+	}
+	for _, test := range tests {
+		scenario := taxScenario(t)
+		scenario.InitialWorld = &World{Resources: map[string]int{"Money": 1000}, Powers: map[string]int{}, Derived: test.derived}
+		_, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.derived, err, test.err)
+		}
+	}
+}
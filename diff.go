@@ -0,0 +1,48 @@
+package main
+
+// Diff returns the signed difference b - a of each resource and power that
+// differs between a and b, treating keys missing from either world as 0.
+// Unchanged keys are left out. Like for flat names in guards, a resource
+// takes precedence over a power of the same name. Fractional resources are
+// compared by DiffReals.
+func Diff(a, b World) map[string]int {
+	diff := make(map[string]int)
+	for _, values := range []struct{ a, b map[string]int }{
+		{a.Powers, b.Powers},
+		{a.Resources, b.Resources},
+	} {
+		for key, value := range values.a {
+			diff[key] = values.b[key] - value
+		}
+		for key, value := range values.b {
+			if _, ok := values.a[key]; !ok {
+				diff[key] = value
+			}
+		}
+	}
+	for key, d := range diff {
+		if d == 0 {
+			delete(diff, key)
+		}
+	}
+	return diff
+}
+
+// DiffReals is like Diff for the fractional resources of a and b.
+func DiffReals(a, b World) map[string]float64 {
+	diff := make(map[string]float64)
+	for key, value := range a.Reals {
+		diff[key] = b.Reals[key] - value
+	}
+	for key, value := range b.Reals {
+		if _, ok := a.Reals[key]; !ok {
+			diff[key] = value
+		}
+	}
+	for key, d := range diff {
+		if d == 0 {
+			delete(diff, key)
+		}
+	}
+	return diff
+}
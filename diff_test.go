@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b World
+		want map[string]int
+	}{
+		{
+			name: "empty",
+			want: map[string]int{},
+		},
+		{
+			name: "unchanged",
+			a:    World{Resources: map[string]int{"Money": 100}, Powers: map[string]int{"Military": 90}},
+			b:    World{Resources: map[string]int{"Money": 100}, Powers: map[string]int{"Military": 90}},
+			want: map[string]int{},
+		},
+		{
+			name: "changed",
+			a:    World{Resources: map[string]int{"Money": 4000}, Powers: map[string]int{"Military": 90}},
+			b:    World{Resources: map[string]int{"Money": 2000}, Powers: map[string]int{"Military": 95}},
+			want: map[string]int{"Money": -2000, "Military": 5},
+		},
+		{
+			name: "added",
+			a:    World{Resources: map[string]int{"Money": 100}},
+			b:    World{Resources: map[string]int{"Money": 100, "Food": 20}, Powers: map[string]int{"Church": 3}},
+			want: map[string]int{"Food": 20, "Church": 3},
+		},
+		{
+			name: "removed",
+			a:    World{Resources: map[string]int{"Money": 100, "Food": 20}},
+			b:    World{Resources: map[string]int{"Money": 100}},
+			want: map[string]int{"Food": -20},
+		},
+		{
+			name: "added as zero",
+			a:    World{},
+			b:    World{Resources: map[string]int{"Food": 0}},
+			want: map[string]int{},
+		},
+		{
+			name: "same name",
+			a:    World{Resources: map[string]int{"Gold": 10}, Powers: map[string]int{"Gold": 1}},
+			b:    World{Resources: map[string]int{"Gold": 15}, Powers: map[string]int{"Gold": 0}},
+			want: map[string]int{"Gold": 5},
+		},
+		{
+			name: "reals ignored",
+			a:    World{Reals: map[string]float64{"Approval": 62.5}},
+			b:    World{Reals: map[string]float64{"Approval": 60.25}},
+			want: map[string]int{},
+		},
+	}
+	for _, test := range tests {
+		if got := Diff(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiffReals(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b World
+		want map[string]float64
+	}{
+		{
+			name: "empty",
+			want: map[string]float64{},
+		},
+		{
+			name: "changed, added and removed",
+			a:    World{Reals: map[string]float64{"Approval": 62.5, "Trust": 1, "Hope": 0.25}},
+			b:    World{Reals: map[string]float64{"Approval": 60.25, "Trust": 1, "Fear": 0.5}},
+			want: map[string]float64{"Approval": -2.25, "Fear": 0.5, "Hope": -0.25},
+		},
+		{
+			name: "integer resources ignored",
+			a:    World{Resources: map[string]int{"Money": 100}},
+			b:    World{Resources: map[string]int{"Money": 200}},
+			want: map[string]float64{},
+		},
+	}
+	for _, test := range tests {
+		if got := DiffReals(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
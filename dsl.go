@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseDSL reads a scenario written in a compact line-based format:
+//
+//	# Comments and blank lines are ignored.
+//	INITIAL Money 4000
+//	INITIAL Powers.Military 90
+//	RULE putsch WHEN World.Resources.Money > 1000 WEIGHT 1
+//	  DECISION Make putsch
+//	  TAGS Military Politics
+//	  CHOICE Accept
+//	    SET Money *0.5 +0
+//	    SET Powers.Legislation *0 +100
+//	  CHOICE Reject
+//
+// SET changes a resource, or a power if its key is prefixed with "Powers.",
+// multiplying it by the *factor and then adding the +term or -term to it.
+// Either may be omitted. INITIAL sets a resource or power of the initial
+// world, which then holds only those set. Indentation is optional. Syntax
+// errors report the line they occur on.
+func ParseDSL(r io.Reader) (Scenario, error) {
+	var p dslParser
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.line++
+		if err := p.parseLine(strings.TrimSpace(scanner.Text())); err != nil {
+			return Scenario{}, fmt.Errorf("line %d: %v", p.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Scenario{}, err
+	}
+	return p.file.Scenario()
+}
+
+type dslParser struct {
+	file scenarioFile
+	line int
+}
+
+func (p *dslParser) parseLine(line string) error {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	keyword, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		keyword, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch keyword {
+	case "RULE":
+		return p.parseRule(rest)
+	case "DECISION":
+		rule, err := p.rule(keyword)
+		if err != nil {
+			return err
+		}
+		if rest == "" {
+			return fmt.Errorf("missing decision description")
+		}
+		if rule.Decision.Description != "" {
+			return fmt.Errorf("rule %v already has a decision", rule.Name)
+		}
+		rule.Decision.Description = rest
+		return nil
+	case "TAGS":
+		rule, err := p.rule(keyword)
+		if err != nil {
+			return err
+		}
+		if rest == "" {
+			return fmt.Errorf("missing tags")
+		}
+		rule.Decision.Tags = append(rule.Decision.Tags, strings.Fields(rest)...)
+		return nil
+	case "CHOICE":
+		rule, err := p.rule(keyword)
+		if err != nil {
+			return err
+		}
+		if rest == "" {
+			return fmt.Errorf("missing choice description")
+		}
+		rule.Decision.Choices = append(rule.Decision.Choices, choiceFile{Description: rest})
+		return nil
+	case "SET":
+		return p.parseSet(rest)
+	case "INITIAL":
+		return p.parseInitial(rest)
+	default:
+		return fmt.Errorf("unknown keyword %q", keyword)
+	}
+}
+
+// parseRule parses "<name> WHEN <guard> WEIGHT <weight>".
+func (p *dslParser) parseRule(s string) error {
+	i := strings.Index(s, " WHEN ")
+	if i < 0 {
+		return fmt.Errorf("expected RULE <name> WHEN <guard> WEIGHT <weight>")
+	}
+	name := strings.TrimSpace(s[:i])
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid rule name %q", name)
+	}
+	s = s[i+len(" WHEN "):]
+	j := strings.LastIndex(s, " WEIGHT ")
+	if j < 0 {
+		return fmt.Errorf("missing WEIGHT")
+	}
+	guard := strings.TrimSpace(s[:j])
+	if guard == "" {
+		return fmt.Errorf("missing guard")
+	}
+	if _, err := NewGuard(guard); err != nil {
+		return fmt.Errorf("invalid guard %q: %v", guard, err)
+	}
+	weight, err := strconv.ParseFloat(strings.TrimSpace(s[j+len(" WEIGHT "):]), 64)
+	if err != nil {
+		return fmt.Errorf("invalid weight: %v", err)
+	}
+	p.file.Rules = append(p.file.Rules, ruleFile{
+		Name:   name,
+		Guard:  guard,
+		Weight: weight,
+	})
+	return nil
+}
+
+// parseSet parses "<key> [*<factor>] [+<term>|-<term>]".
+func (p *dslParser) parseSet(s string) error {
+	rule, err := p.rule("SET")
+	if err != nil {
+		return err
+	}
+	if len(rule.Decision.Choices) == 0 {
+		return fmt.Errorf("SET outside of a CHOICE")
+	}
+	choice := &rule.Decision.Choices[len(rule.Decision.Choices)-1]
+
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return fmt.Errorf("expected SET <key> *<factor> +<term>")
+	}
+	delta := deltaFile{1, 0}
+	var factor, term bool
+	for _, field := range fields[1:] {
+		switch field[0] {
+		case '*':
+			if factor {
+				return fmt.Errorf("duplicate factor %q", field)
+			}
+			factor = true
+			v, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil {
+				return fmt.Errorf("invalid factor %q", field)
+			}
+			delta[0] = v
+		case '+', '-':
+			if term {
+				return fmt.Errorf("duplicate term %q", field)
+			}
+			term = true
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return fmt.Errorf("invalid term %q", field)
+			}
+			delta[1] = v
+		default:
+			return fmt.Errorf("unexpected %q, expected *<factor> or +<term>", field)
+		}
+	}
+
+	key := fields[0]
+	values := &choice.Change.Resources
+	if strings.HasPrefix(key, "Powers.") {
+		key = strings.TrimPrefix(key, "Powers.")
+		values = &choice.Change.Powers
+	}
+	if key == "" {
+		return fmt.Errorf("missing key")
+	}
+	if *values == nil {
+		*values = make(map[string]deltaFile)
+	}
+	if _, ok := (*values)[key]; ok {
+		return fmt.Errorf("%v is already set", fields[0])
+	}
+	(*values)[key] = delta
+	return nil
+}
+
+// parseInitial parses "<key> <value>".
+func (p *dslParser) parseInitial(s string) error {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return fmt.Errorf("expected INITIAL <key> <value>")
+	}
+	value, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("invalid value %q", fields[1])
+	}
+	if p.file.InitialWorld == nil {
+		p.file.InitialWorld = &worldFile{Resources: map[string]int{}, Powers: map[string]int{}}
+	}
+	key := fields[0]
+	values := p.file.InitialWorld.Resources
+	if strings.HasPrefix(key, "Powers.") {
+		key = strings.TrimPrefix(key, "Powers.")
+		values = p.file.InitialWorld.Powers
+	}
+	if key == "" {
+		return fmt.Errorf("missing key")
+	}
+	if _, ok := values[key]; ok {
+		return fmt.Errorf("%v is already set", fields[0])
+	}
+	values[key] = value
+	return nil
+}
+
+// rule returns the rule being parsed, failing if keyword appears before any
+// RULE.
+func (p *dslParser) rule(keyword string) (*ruleFile, error) {
+	if len(p.file.Rules) == 0 {
+		return nil, fmt.Errorf("%v outside of a RULE", keyword)
+	}
+	return &p.file.Rules[len(p.file.Rules)-1], nil
+}
+
+func loadScenarioDSL(path string) (Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	defer f.Close()
+	scenario, err := ParseDSL(f)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%v: %v", path, err)
+	}
+	return scenario, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDSL(t *testing.T) {
+	tests := []struct {
+		name string
+		dsl  string
+		want scenarioFile
+	}{
+		{
+			name: "empty",
+			dsl:  "# Nothing here.\n\n",
+			want: scenarioFile{},
+		},
+		{
+			name: "factor and term",
+			dsl: `RULE tax WHEN World.Resources.Money < 1000 WEIGHT 0.5
+DECISION Tax
+CHOICE Raise
+SET Money *1.1 +10
+SET Powers.Legislation -5
+CHOICE Ignore`,
+			want: scenarioFile{Rules: []ruleFile{{
+				Name:   "tax",
+				Guard:  "World.Resources.Money < 1000",
+				Weight: 0.5,
+				Decision: decisionFile{
+					Description: "Tax",
+					Choices: []choiceFile{
+						{
+							Description: "Raise",
+							Change: changeFile{
+								Resources: map[string]deltaFile{"Money": {1.1, 10}},
+								Powers:    map[string]deltaFile{"Legislation": {1, -5}},
+							},
+						},
+						{Description: "Ignore"},
+					},
+				},
+			}}},
+		},
+		{
+			name: "tags",
+			dsl:  "RULE a WHEN true WEIGHT 1\nTAGS Economy Military\nTAGS Diplomacy\nCHOICE Accept",
+			want: scenarioFile{Rules: []ruleFile{{
+				Name:   "a",
+				Guard:  "true",
+				Weight: 1,
+				Decision: decisionFile{
+					Tags:    []string{"Economy", "Military", "Diplomacy"},
+					Choices: []choiceFile{{Description: "Accept"}},
+				},
+			}}},
+		},
+		{
+			name: "factor only",
+			dsl:  "RULE a WHEN true WEIGHT 1\n\tCHOICE Halve\n\t\tSET Money *0.5",
+			want: scenarioFile{Rules: []ruleFile{{
+				Name:   "a",
+				Guard:  "true",
+				Weight: 1,
+				Decision: decisionFile{Choices: []choiceFile{{
+					Description: "Halve",
+					Change:      changeFile{Resources: map[string]deltaFile{"Money": {0.5, 0}}},
+				}}},
+			}}},
+		},
+		{
+			name: "WEIGHT in guard",
+			dsl:  `RULE a WHEN "x WEIGHT y" != "" WEIGHT 0.2` + "\nCHOICE Accept",
+			want: scenarioFile{Rules: []ruleFile{{
+				Name:     "a",
+				Guard:    `"x WEIGHT y" != ""`,
+				Weight:   0.2,
+				Decision: decisionFile{Choices: []choiceFile{{Description: "Accept"}}},
+			}}},
+		},
+		{
+			name: "initial world",
+			dsl:  "INITIAL Money 4000\nINITIAL Popularity -5\nINITIAL Powers.Military 90",
+			want: scenarioFile{InitialWorld: &worldFile{
+				Resources: map[string]int{"Money": 4000, "Popularity": -5},
+				Powers:    map[string]int{"Military": 90},
+			}},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseDSL(strings.NewReader(test.dsl))
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		want, err := test.want.Scenario()
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %+v, want %+v", test.name, got, want)
+		}
+	}
+}
+
+func TestParseDSLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		dsl  string
+		err  string
+	}{
+		{"unknown keyword", "RULE a WHEN true WEIGHT 1\nSPEND Money", `line 2: unknown keyword "SPEND"`},
+		{"lowercase keyword", "rule a WHEN true WEIGHT 1", `line 1: unknown keyword "rule"`},
+		{"missing WHEN", "RULE a WEIGHT 1", "line 1: expected RULE <name> WHEN <guard> WEIGHT <weight>"},
+		{"missing WEIGHT", "RULE a WHEN true", "line 1: missing WEIGHT"},
+		{"name with spaces", "RULE a b WHEN true WEIGHT 1", `line 1: invalid rule name "a b"`},
+		{"missing guard", "RULE a WHEN  WEIGHT 1", "line 1: missing guard"},
+		{"invalid guard", "RULE a WHEN World.Resources.Money > WEIGHT 1", `line 1: invalid guard "World.Resources.Money >"`},
+		{"invalid weight", "RULE a WHEN true WEIGHT heavy", "line 1: invalid weight"},
+		{"decision outside rule", "DECISION Tax", "line 1: DECISION outside of a RULE"},
+		{"missing decision", "RULE a WHEN true WEIGHT 1\nDECISION", "line 2: missing decision description"},
+		{"second decision", "RULE a WHEN true WEIGHT 1\nDECISION A\nDECISION B", "line 3: rule a already has a decision"},
+		{"missing tags", "RULE a WHEN true WEIGHT 1\nTAGS", "line 2: missing tags"},
+		{"missing choice", "RULE a WHEN true WEIGHT 1\nCHOICE", "line 2: missing choice description"},
+		{"set outside choice", "RULE a WHEN true WEIGHT 1\nSET Money +1", "line 2: SET outside of a CHOICE"},
+		{"set without delta", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money", "line 3: expected SET <key> *<factor> +<term>"},
+		{"duplicate factor", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money *1 *2", `line 3: duplicate factor "*2"`},
+		{"duplicate term", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money +1 -2", `line 3: duplicate term "-2"`},
+		{"invalid factor", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money *x", `line 3: invalid factor "*x"`},
+		{"invalid term", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money +x", `line 3: invalid term "+x"`},
+		{"unexpected field", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money 5", `line 3: unexpected "5"`},
+		{"missing key", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Powers. +1", "line 3: missing key"},
+		{"key set twice", "RULE a WHEN true WEIGHT 1\nCHOICE A\nSET Money +1\nSET Money *2", "line 4: Money is already set"},
+		{"initial without value", "INITIAL Money", "line 1: expected INITIAL <key> <value>"},
+		{"invalid initial value", "INITIAL Money lots", `line 1: invalid value "lots"`},
+		{"missing initial key", "INITIAL Powers. 5", "line 1: missing key"},
+		{"initial set twice", "INITIAL Powers.Military 5\nINITIAL Powers.Military 6", "line 2: Powers.Military is already set"},
+		{"duplicate rule names", "RULE a WHEN true WEIGHT 1\nCHOICE A\nRULE a WHEN false WEIGHT 1\nCHOICE A", "rule 1 (a): duplicate name"},
+	}
+	for _, test := range tests {
+		_, err := ParseDSL(strings.NewReader(test.dsl))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestDSLAndJSONScenariosEqual(t *testing.T) {
+	f, err := os.Open("scenarios/simple.pol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fromDSL, err := ParseDSL(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := LoadScenario("scenarios/simple.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromDSL, fromJSON) {
+		t.Errorf("got %+v from the DSL, want %+v as from JSON", fromDSL, fromJSON)
+	}
+}
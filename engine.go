@@ -0,0 +1,628 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// GameConfig configures a game.
+type GameConfig struct {
+	// FallbackDecision is offered when no rule passes so the game doesn't get
+	// stuck. If nil, the game ends instead.
+	FallbackDecision *Decision
+	// Seed seeds the random number generator. If zero, the current time is
+	// used.
+	Seed int64
+	// MaxUndo is the number of turns that can be undone. If zero, undo is
+	// disabled.
+	MaxUndo int
+	// MaxDecisions is the maximum number of decisions offered per turn. If
+	// zero, 3 decisions are offered.
+	MaxDecisions int
+	// WinConditions and LoseConditions are guard expressions evaluated
+	// after each choice; the first one to pass ends the game.
+	WinConditions  []string
+	LoseConditions []string
+	// Tags, if set, restricts the decisions offered to those having at
+	// least one of the tags.
+	Tags []string
+	// BudgetResource, if set, is a resource, e.g. "Money", that choices
+	// can't drive below zero.
+	BudgetResource string
+	// AllowSkip offers skipDecision in addition to the decisions of the
+	// turn unless MaxDecisions are offered already, letting the player
+	// decline to act.
+	AllowSkip bool
+	// MaxTurns ends the game with a TurnLimit result once that many turns
+	// have been played. If zero, the number of turns is unlimited.
+	MaxTurns int
+	// Growth compounds resources and powers by a rate at the end of every
+	// turn, before the scenario's decay, e.g. {"Money": 0.05} for 5%
+	// interest. The values are rounded and clamped to their bounds.
+	Growth map[string]float64
+	// Clock tells the time for time-based scheduled events and expiring
+	// decisions. If nil, the system time is used. Times aren't saved: a
+	// resumed game starts anew.
+	Clock Clock
+	// Probability, if set, decides whether candidate decisions are offered
+	// instead of LinearProbability, if the scenario selects them in the
+	// Independent mode.
+	Probability ProbabilityFunc
+	// MaxSnapshots is the number of past turns whose world is kept for
+	// SnapshotAt and the history guard function. If zero, 32 are kept.
+	MaxSnapshots int
+	// MaxEvents is the number of latest events kept for Engine.Events. If
+	// zero, none are kept: handlers registered with Engine.OnEvent see
+	// every event without the engine's memory growing with the game.
+	MaxEvents int
+}
+
+type Outcome int
+
+const (
+	Win Outcome = iota + 1
+	Lose
+	// TurnLimit ends games reaching GameConfig.MaxTurns.
+	TurnLimit
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "win"
+	case Lose:
+		return "lose"
+	case TurnLimit:
+		return "turn limit"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for _, outcome := range []Outcome{Win, Lose, TurnLimit} {
+		if outcome.String() == string(text) {
+			*o = outcome
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+// GameResult describes how a game ended.
+type GameResult struct {
+	Outcome Outcome
+	// Condition is the source of the condition that ended the game, if
+	// any.
+	Condition string
+}
+
+type condition struct {
+	Guard
+	Outcome
+}
+
+func compileConditions(cfg GameConfig) ([]condition, error) {
+	conditions := make([]condition, 0, len(cfg.WinConditions)+len(cfg.LoseConditions))
+	for _, outcome := range []struct {
+		Outcome
+		sources []string
+	}{{Win, cfg.WinConditions}, {Lose, cfg.LoseConditions}} {
+		for _, source := range outcome.sources {
+			guard, err := NewGuard(source)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %v condition %q: %v", outcome.Outcome, source, err)
+			}
+			conditions = append(conditions, condition{guard, outcome.Outcome})
+		}
+	}
+	return conditions, nil
+}
+
+func checkConditions(conditions []condition, world World) (*GameResult, error) {
+	for _, c := range conditions {
+		pass, err := c.Pass(world)
+		if err != nil {
+			return nil, err
+		}
+		if pass {
+			return &GameResult{Outcome: c.Outcome, Condition: c.Source}, nil
+		}
+	}
+	return nil, nil
+}
+
+const defaultMaxDecisions = 3
+
+// skipDecision is offered when GameConfig.AllowSkip is set. Choosing it
+// ends the turn without changing anything.
+var skipDecision = Decision{
+	Description: "Do nothing",
+	Key:         "skip",
+	Choices: []Choice{
+		{Description: "Skip", Key: "skip"},
+	},
+}
+
+// maxChainDepth is the maximum number of follow-up decisions offered in a
+// turn, so that a cycle of Choice.Next can't stall the game. Further ones
+// are ignored and the turn ends.
+const maxChainDepth = 10
+
+// Engine runs a game synchronously, without any UI.
+//
+// Each engine owns its world, random number generator and rule state, so
+// distinct engines, even sharing a scenario, can be used from different
+// goroutines in parallel. An engine's methods, however, must not be called
+// concurrently; Choose and Undo panic if they detect it.
+type Engine struct {
+	scenario   Scenario
+	cfg        GameConfig
+	conditions []condition
+	src        *countingSource
+	rand       *rand.Rand
+	state      RuleState
+	world      World
+	decisions  []Decision
+	// trace explains how the current decisions were selected.
+	trace DecisionTrace
+	// offerDraws is the number of random values drawn before the current
+	// decisions were offered.
+	offerDraws uint64
+	// chain is the number of follow-up decisions offered this turn.
+	chain int
+	clock Clock
+	// started is when the game started, elapsed the time since then at
+	// the start of the turn and offeredAt when the current decisions were
+	// offered.
+	started   time.Time
+	elapsed   time.Duration
+	offeredAt time.Time
+	// pending are the decisions kept on offer until they expire.
+	pending []pendingDecision
+	result  *GameResult
+	// history holds snapshots taken before each choice, most recent last.
+	history  []snapshot
+	events   []Event
+	handlers []handler
+	// busy is set while a method modifying the engine runs.
+	busy int32
+	// past holds the worlds at the start of recent turns.
+	past        pastWorlds
+	subscribers []subscriber
+	// lastSubscriber is the id of the last subscriber or event handler
+	// registered.
+	lastSubscriber int
+}
+
+// snapshot captures the engine state at the start of a turn.
+type snapshot struct {
+	world      World
+	state      RuleState
+	decisions  []Decision
+	trace      DecisionTrace
+	offerDraws uint64
+	chain      int
+	elapsed    time.Duration
+	pending    []pendingDecision
+	draws      uint64
+}
+
+func NewEngine(scenario Scenario, cfg GameConfig) (*Engine, error) {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	world := scenario.initialWorld()
+	world.recordChange(world)
+	return newEngine(scenario, cfg, world, NewRuleState(), newCountingSource(seed, 0))
+}
+
+func newEngine(scenario Scenario, cfg GameConfig, world World, state RuleState, src *countingSource) (*Engine, error) {
+	conditions, err := compileConditions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxDecisions == 0 {
+		cfg.MaxDecisions = defaultMaxDecisions
+	}
+	if cfg.Probability != nil && scenario.Selector == nil && scenario.Mode == Independent {
+		scenario.Selector = probabilitySelector{cfg.Probability}
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	if err := world.updateDerived(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		scenario:   scenario,
+		cfg:        cfg,
+		conditions: conditions,
+		src:        src,
+		rand:       rand.New(src),
+		state:      state,
+		world:      world,
+		clock:      clock,
+		started:    clock.Now(),
+	}
+	e.past.max = cfg.MaxSnapshots
+	e.world.past = &e.past
+	e.world.state = &e.state
+	e.emit(Event{Kind: WorldInitialized})
+	if err := e.offer(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Current returns the current world. It must not be modified.
+func (e *Engine) Current() World {
+	return e.world
+}
+
+// Seed returns the seed the game was started with, drawn from the clock if
+// GameConfig.Seed was zero. Replaying the game requires it.
+func (e *Engine) Seed() int64 {
+	return e.src.seed
+}
+
+// Decisions returns the decisions offered for the current turn. It's empty
+// once the game is over.
+func (e *Engine) Decisions() []Decision {
+	return e.decisions
+}
+
+// DecisionTrace explains how the current decisions were selected. It's
+// empty for follow-up decisions.
+func (e *Engine) DecisionTrace() DecisionTrace {
+	return e.trace
+}
+
+// Result returns how the game ended, or nil if no win or lose condition has
+// passed yet.
+func (e *Engine) Result() *GameResult {
+	return e.result
+}
+
+// Choose applies choice, one of those offered, to the world, ending the
+// turn unless the choice has a follow-up decision, which is then the only
+// one offered. choice must come from Decisions; only its position and
+// description are used to identify the offered choice applied. If it
+// fails, the engine is rolled back to where it was, although event
+// handlers may have been called.
+func (e *Engine) Choose(choice Choice) error {
+	defer e.enter()()
+	if len(e.decisions) == 0 {
+		return fmt.Errorf("game is over")
+	}
+	i, j, ok := e.decisionOf(choice)
+	if !ok {
+		return fmt.Errorf("choice %v is not offered", choice.Description)
+	}
+	decision := e.decisions[i]
+	if e.expired(decision) {
+		return fmt.Errorf("decision %v expired", decision.Description)
+	}
+	choice = decision.Choices[j]
+	if !e.CanAfford(choice) {
+		return fmt.Errorf("can't afford %v", choice.Description)
+	}
+	undo := e.snapshot()
+	restore := e.checkpoint()
+	fail := func(err error) error {
+		restore()
+		return err
+	}
+	world := e.world.Copy()
+	if err := world.Apply(choice, e.rand); err != nil {
+		return fail(err)
+	}
+	e.push(undo)
+	e.settle(decision)
+	before := e.world
+	e.world = world
+	if e.chain == 0 {
+		e.past.push(before)
+	}
+	e.record(choice)
+	var next *Decision
+	if choice.Next != nil && e.chain < maxChainDepth {
+		next = choice.Next
+		e.chain++
+	} else if err := e.endTurn(); err != nil {
+		return fail(err)
+	}
+	e.emit(Event{Kind: ChoiceApplied, Before: before, Choice: &choice})
+	if err := e.conclude(next); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// conclude ends the game if a condition passes after a choice, or offers
+// the next decisions otherwise: next if set, or those of the turn.
+func (e *Engine) conclude(next *Decision) error {
+	result, err := checkConditions(e.conditions, e.world)
+	if err != nil {
+		return err
+	}
+	if result == nil && e.cfg.MaxTurns > 0 && e.world.Turn >= e.cfg.MaxTurns {
+		result = &GameResult{Outcome: TurnLimit}
+	}
+	if result != nil {
+		e.result = result
+		e.decisions = nil
+		e.emit(Event{Kind: GameEnded, Result: result})
+		return nil
+	}
+	if next != nil {
+		return e.offerNext(*next)
+	}
+	return e.offer()
+}
+
+// record records that choice was made this turn.
+func (e *Engine) record(choice Choice) {
+	e.state.Fired(choice, e.world.Turn)
+	e.state.Chosen = append(e.state.Chosen, ChosenChoice{Rule: e.ruleName(choice), Choice: choice.Description})
+}
+
+// ruleName returns the name of the rule that offered choice, or "" if it
+// wasn't offered by a rule.
+func (e *Engine) ruleName(choice Choice) string {
+	if choice.rule == 0 {
+		return ""
+	}
+	return e.scenario.Rules[choice.rule-1].Name
+}
+
+// RuleStats returns the number of times a decision of each rule was chosen
+// so far, by rule name. Unnamed rules are reported by index.
+func (e *Engine) RuleStats() map[string]int {
+	stats := make(map[string]int, len(e.state.Fires))
+	for i, n := range e.state.Fires {
+		name := e.scenario.Rules[i].Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		stats[name] = n
+	}
+	return stats
+}
+
+// SnapshotAt returns a copy of the world as it was at the start of turn,
+// which may be the current one, or false if it's no longer kept; see
+// GameConfig.MaxSnapshots. Turns undone are forgotten.
+func (e *Engine) SnapshotAt(turn int) (World, bool) {
+	if world, ok := e.past.at(turn); ok {
+		return world.Copy(), true
+	}
+	if turn == e.world.Turn && e.chain == 0 {
+		world := e.world.Copy()
+		world.past, world.state = nil, nil
+		return world, true
+	}
+	return World{}, false
+}
+
+// Undo reverts the last choice, restoring the world and the decisions that
+// were offered before it was made.
+func (e *Engine) Undo() error {
+	defer e.enter()()
+	if len(e.history) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+	last := e.history[len(e.history)-1]
+	e.history = e.history[:len(e.history)-1]
+	e.restore(last)
+	// The decisions are offered anew.
+	e.offeredAt = e.clock.Now()
+	if last.chain == 0 && e.past.keeps(last.world.Turn) {
+		e.past.pop()
+	}
+	e.result = nil
+	e.notify()
+	return nil
+}
+
+// enter marks the engine as busy, panicking if it already is, and returns
+// a function marking it as idle again.
+func (e *Engine) enter() func() {
+	if !atomic.CompareAndSwapInt32(&e.busy, 0, 1) {
+		panic("politika: concurrent use of Engine")
+	}
+	return func() { atomic.StoreInt32(&e.busy, 0) }
+}
+
+// snapshot returns the state Undo goes back to, or nil if undo is
+// disabled.
+func (e *Engine) snapshot() *snapshot {
+	if e.cfg.MaxUndo <= 0 {
+		return nil
+	}
+	s := e.capture()
+	return &s
+}
+
+// capture returns the current state of the engine.
+func (e *Engine) capture() snapshot {
+	return snapshot{
+		world:      e.world.Copy(),
+		state:      e.state.Copy(),
+		decisions:  e.decisions,
+		trace:      e.trace,
+		offerDraws: e.offerDraws,
+		chain:      e.chain,
+		elapsed:    e.elapsed,
+		pending:    e.pending,
+		draws:      e.src.draws,
+	}
+}
+
+// restore puts the engine back in state s.
+func (e *Engine) restore(s snapshot) {
+	e.world = s.world
+	e.state = s.state
+	e.decisions = s.decisions
+	e.trace = s.trace
+	e.offerDraws = s.offerDraws
+	e.chain = s.chain
+	e.elapsed = s.elapsed
+	e.pending = s.pending
+	e.rewind(s.draws)
+}
+
+// checkpoint returns a function rolling the engine back to its current
+// state, for methods failing part way.
+func (e *Engine) checkpoint() (rollback func()) {
+	s := e.capture()
+	history, past, events := e.history, e.past.worlds, e.events
+	result, offeredAt := e.result, e.offeredAt
+	return func() {
+		e.restore(s)
+		e.history, e.past.worlds, e.events = history, past, events
+		e.result, e.offeredAt = result, offeredAt
+		e.notify()
+	}
+}
+
+// push keeps s, if any, for Undo.
+func (e *Engine) push(s *snapshot) {
+	if s == nil {
+		return
+	}
+	if len(e.history) == e.cfg.MaxUndo {
+		e.history = e.history[1:]
+	}
+	e.history = append(e.history, *s)
+}
+
+// rewind makes the random numbers drawn since the source had drawn draws
+// be drawn again.
+func (e *Engine) rewind(draws uint64) {
+	if e.src.draws == draws {
+		return
+	}
+	e.src = newCountingSource(e.src.seed, draws)
+	e.rand = rand.New(e.src)
+}
+
+func (e *Engine) offer() error {
+	if err := e.expirePending(); err != nil {
+		return err
+	}
+	e.offerDraws = e.src.draws
+	for i, rule := range e.scenario.Rules {
+		if !e.state.Available(i, rule, e.world.Turn) {
+			e.emit(Event{Kind: RuleSkippedByCooldown, Rule: rule.Name})
+		}
+	}
+	now := e.clock.Now()
+	span := timeSpan{e.elapsed, now.Sub(e.started)}
+	e.elapsed = span.to
+	e.offeredAt = now
+	var trace DecisionTrace
+	decisions, err := e.scenario.decisions(e.rand, e.state, e.world, e.cfg.MaxDecisions, span, &trace)
+	if err != nil {
+		return err
+	}
+	e.trace = trace
+	decisions = e.withPending(filterByTags(decisions, e.cfg.Tags))
+	if len(decisions) == 0 && e.cfg.FallbackDecision != nil {
+		decisions = []Decision{*e.cfg.FallbackDecision}
+	}
+	if e.cfg.AllowSkip && len(decisions) < e.cfg.MaxDecisions {
+		decisions = append(decisions, skipDecision)
+	}
+	if len(decisions) > 0 {
+		decisions = append(decisions, e.scenario.AlwaysAvailable...)
+	}
+	e.decisions = locate(decisions)
+	if len(decisions) == 0 {
+		e.emit(Event{Kind: GameEnded})
+	} else {
+		e.emit(Event{Kind: DecisionsOffered, Decisions: decisions, Trace: &trace})
+	}
+	return nil
+}
+
+// endTurn starts the next turn, growing and decaying the world.
+func (e *Engine) endTurn() error {
+	e.chain = 0
+	e.world.Turn++
+	if err := e.world.grow(e.cfg.Growth, e.rand); err != nil {
+		return err
+	}
+	return e.world.decay(e.scenario.Decay, e.rand)
+}
+
+// offerNext offers the follow-up decision of a choice. If none of its
+// choices is available, the turn ends instead.
+func (e *Engine) offerNext(next Decision) error {
+	decision, err := next.availableChoices(e.world)
+	if err != nil {
+		return err
+	}
+	if len(decision.Choices) == 0 {
+		if err := e.endTurn(); err != nil {
+			return err
+		}
+		return e.offer()
+	}
+	e.decisions = locate([]Decision{decision})
+	e.offeredAt = e.clock.Now()
+	e.trace = DecisionTrace{}
+	e.emit(Event{Kind: DecisionsOffered, Decisions: e.decisions})
+	return nil
+}
+
+// locate returns copies of decisions whose choices know their position, as
+// offered.
+func locate(decisions []Decision) []Decision {
+	located := make([]Decision, len(decisions))
+	for i, decision := range decisions {
+		choices := make([]Choice, len(decision.Choices))
+		for j, choice := range decision.Choices {
+			choice.offered = offerPosition{i + 1, j}
+			choices[j] = choice
+		}
+		decision.Choices = choices
+		located[i] = decision
+	}
+	return located
+}
+
+// countingSource is a rand.Source that counts the values drawn from it so
+// that its stream can be reproduced from the seed.
+type countingSource struct {
+	rand.Source
+	seed  int64
+	draws uint64
+}
+
+// newCountingSource returns a source seeded with seed that has already
+// drawn the given number of values.
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	src := &countingSource{
+		Source: rand.NewSource(seed),
+		seed:   seed,
+	}
+	for src.draws < draws {
+		src.Int63()
+	}
+	return src
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.Source.Int63()
+}
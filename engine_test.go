@@ -0,0 +1,626 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func taxScenario(t testing.TB) Scenario {
+	t.Helper()
+	tax := Decision{Description: "Tax", Choices: []Choice{
+		{Description: "Raise", Change: Change{Resources: map[string]Delta{"Money": {100, 0, float64(OpAdd)}}}},
+		{Description: "Lower", Change: Change{Resources: map[string]Delta{"Money": {-100, 0, float64(OpAdd)}}}},
+	}}
+	return Scenario{Rules: []Rule{mustRule(t, "tax", "true", 1, tax)}}
+}
+
+func TestEnginePlay(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		choice int
+		money  int
+	}{
+		{0, 4100},
+		{0, 4200},
+		{1, 4100},
+		{0, 4200},
+	}
+	for turn, test := range tests {
+		decisions := e.Decisions()
+		if got := descriptions(decisions); fmt.Sprint(got) != "[Tax]" {
+			t.Fatalf("turn %v: got decisions %v, want [Tax]", turn, got)
+		}
+		if err := e.Choose(decisions[0].Choices[test.choice]); err != nil {
+			t.Fatalf("turn %v: %v", turn, err)
+		}
+		world := e.Current()
+		if world.Turn != turn+1 || world.Resources["Money"] != test.money {
+			t.Errorf("turn %v: got turn %v and Money %v, want %v and %v", turn, world.Turn, world.Resources["Money"], turn+1, test.money)
+		}
+	}
+	if e.Result() != nil {
+		t.Errorf("got result %+v, want none", e.Result())
+	}
+}
+
+func TestEngineResult(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, WinConditions: []string{"World.Resources.Money >= 4200"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 0; turn < 2; turn++ {
+		if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+			t.Fatalf("turn %v: %v", turn, err)
+		}
+	}
+	want := GameResult{Outcome: Win, Condition: "World.Resources.Money >= 4200"}
+	if got := e.Result(); got == nil || *got != want {
+		t.Errorf("got result %+v, want %+v", got, want)
+	}
+	if len(e.Decisions()) != 0 {
+		t.Errorf("got decisions %v after the game ended", descriptions(e.Decisions()))
+	}
+	if err := e.Choose(Choice{Description: "Raise"}); err == nil {
+		t.Errorf("got no error choosing after the game ended")
+	}
+}
+
+func TestEngineUndo(t *testing.T) {
+	scenario := taxScenario(t)
+	scenario.Rules[0].Decision.Choices[0].Change.Powers = map[string]Delta{"Military": {0.5, 0}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := e.Current().Copy()
+	offered := descriptions(e.Decisions())
+	if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Current(); got.Resources["Money"] == start.Resources["Money"] || got.Turn != 1 {
+		t.Fatalf("got %+v, want the choice applied", got)
+	}
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Current(); !reflect.DeepEqual(got, start) {
+		t.Errorf("got %+v after undo, want %+v", got, start)
+	}
+	if got := descriptions(e.Decisions()); fmt.Sprint(got) != fmt.Sprint(offered) {
+		t.Errorf("got decisions %v after undo, want %v", got, offered)
+	}
+	if err := e.Undo(); err == nil {
+		t.Errorf("got no error undoing past the start")
+	}
+}
+
+func TestEngineMaxUndo(t *testing.T) {
+	tests := []struct {
+		maxUndo int
+		turns   int
+		undos   int
+	}{
+		{0, 3, 0},
+		{2, 1, 1},
+		{2, 3, 2},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxUndo: test.maxUndo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for turn := 0; turn < test.turns; turn++ {
+			if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		undos := 0
+		for e.Undo() == nil {
+			undos++
+		}
+		if undos != test.undos {
+			t.Errorf("max %v after %v turns: got %v undos, want %v", test.maxUndo, test.turns, undos, test.undos)
+		}
+		if got, want := e.Current().Turn, test.turns-test.undos; got != want {
+			t.Errorf("max %v after %v turns: got turn %v, want %v", test.maxUndo, test.turns, got, want)
+		}
+	}
+}
+
+// playEngine plays turns of a new engine, always making the first choice,
+// and returns the final world.
+func playEngine(scenario Scenario, seed int64, turns int) (World, error) {
+	e, err := NewEngine(scenario, GameConfig{Seed: seed, FallbackDecision: &Decision{Choices: []Choice{{Description: "Wait"}}}})
+	if err != nil {
+		return World{}, err
+	}
+	for turn := 0; turn < turns; turn++ {
+		if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+			return World{}, err
+		}
+	}
+	return e.Current(), nil
+}
+
+func TestConcurrentEngines(t *testing.T) {
+	const engines = 50
+	scenario := randomScenario(t)
+	want := make([]World, engines)
+	for i := range want {
+		world, err := playEngine(scenario, int64(i+1), 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = world
+	}
+
+	got := make([]World, engines)
+	errs := make([]error, engines)
+	wait := sync.WaitGroup{}
+	for i := range got {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			got[i], errs[i] = playEngine(scenario, int64(i+1), 20)
+		}(i)
+	}
+	wait.Wait()
+	for i := range got {
+		if errs[i] != nil {
+			t.Fatalf("engine %v: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("engine %v: got %v when run concurrently, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEngineConcurrentUse(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxUndo: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Choose", func() error { return e.Choose(e.Decisions()[0].Choices[0]) }},
+		{"Undo", e.Undo},
+	}
+	for _, test := range tests {
+		func() {
+			done := e.enter()
+			defer done()
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: got no panic on concurrent use", test.name)
+				}
+			}()
+			test.call()
+		}()
+	}
+	if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+		t.Errorf("got %v after the engine became idle again", err)
+	}
+}
+
+func TestLastChangeGuard(t *testing.T) {
+	spend := Decision{Description: "Spend", Choices: []Choice{
+		{Description: "Splurge", Change: Change{Resources: map[string]Delta{"Money": {-1500, 0, float64(OpAdd)}}}},
+		{Description: "Save", Change: Change{Resources: map[string]Delta{"Money": {-500, 0, float64(OpAdd)}}}},
+	}}
+	crash := Decision{Description: "Crash", Choices: []Choice{{Description: "Accept"}}}
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "spend", "true", 1, spend),
+		mustRule(t, "crash", "World.LastChange.Money < -1000", 1, crash),
+	}}
+	tests := []struct {
+		choice string
+		want   string
+	}{
+		{"", "[Spend]"},
+		{"Save", "[Spend]"},
+		{"Splurge", "[Crash Spend]"},
+		{"Save", "[Spend]"},
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn, test := range tests {
+		if test.choice != "" {
+			mustChoose(t, e, test.choice)
+		}
+		if got := fmt.Sprint(descriptions(e.Decisions())); got != test.want {
+			t.Errorf("turn %v: got %v, want %v", turn, got, test.want)
+		}
+	}
+}
+
+func TestLastChange(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 1000, "Popularity": 30},
+		Powers:    map[string]int{"Military": 90},
+	}
+	change := Change{
+		Resources: map[string]Delta{"Money": {0.5, 0}},
+		Powers:    map[string]Delta{"Military": {1, 5}},
+	}
+	if err := world.Apply(Choice{Change: change}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"Money": -500, "Popularity": 0, "Military": 5}
+	if !reflect.DeepEqual(world.LastChange, want) {
+		t.Errorf("got %v, want %v", world.LastChange, want)
+	}
+	copy := world.Copy()
+	copy.LastChange["Money"] = 0
+	if world.LastChange["Money"] != -500 {
+		t.Errorf("got LastChange shared with a copy")
+	}
+}
+
+func TestChainedDecisions(t *testing.T) {
+	occupy := Decision{Description: "Occupy", Choices: []Choice{{Description: "Stay"}, {Description: "Leave"}}}
+	war := Decision{Description: "War", Choices: []Choice{
+		{Description: "Invade", Next: &occupy},
+		{Description: "Negotiate"},
+	}}
+	scenario := Scenario{Rules: []Rule{mustRule(t, "war", "true", 1, war)}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		choice string
+		want   string
+		turn   int
+	}{
+		{"Invade", "[Occupy]", 0},
+		{"Stay", "[War]", 1},
+		{"Negotiate", "[War]", 2},
+		{"Invade", "[Occupy]", 2},
+	}
+	for _, test := range tests {
+		mustChoose(t, e, test.choice)
+		if got := fmt.Sprint(descriptions(e.Decisions())); got != test.want || e.Current().Turn != test.turn {
+			t.Errorf("%v: got %v on turn %v, want %v on turn %v", test.choice, got, e.Current().Turn, test.want, test.turn)
+		}
+	}
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Invade")
+	mustChoose(t, e, "Leave")
+	if got := fmt.Sprint(descriptions(e.Decisions())); got != "[War]" || e.Current().Turn != 3 {
+		t.Errorf("got %v on turn %v after undoing, want [War] on turn 3", got, e.Current().Turn)
+	}
+}
+
+func TestChainDepth(t *testing.T) {
+	loop := &Decision{Description: "Loop", Choices: []Choice{{Description: "Again"}}}
+	loop.Choices[0].Next = loop
+	scenario := Scenario{Rules: []Rule{mustRule(t, "loop", "true", 1, *loop)}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxChainDepth; i++ {
+		mustChoose(t, e, "Again")
+		if e.Current().Turn != 0 {
+			t.Fatalf("got turn %v after %v follow-ups, want 0", e.Current().Turn, i+1)
+		}
+	}
+	mustChoose(t, e, "Again")
+	if e.Current().Turn != 1 {
+		t.Errorf("got turn %v past the chain depth, want 1", e.Current().Turn)
+	}
+}
+
+func TestAllowSkip(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       GameConfig
+		decisions string
+	}{
+		{"disabled", GameConfig{Seed: 1}, "[Tax]"},
+		{"enabled", GameConfig{Seed: 1, AllowSkip: true}, "[Tax Do nothing]"},
+		{"full", GameConfig{Seed: 1, AllowSkip: true, MaxDecisions: 1}, "[Tax]"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != test.decisions {
+			t.Errorf("%v: got decisions %v, want %v", test.name, got, test.decisions)
+		}
+	}
+
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, AllowSkip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := e.Current()
+	if err := e.Choose(findChoice(t, e.Decisions(), skipDecision.Choices[0].Description)); err != nil {
+		t.Fatal(err)
+	}
+	after := e.Current()
+	if after.Turn != before.Turn+1 {
+		t.Errorf("got turn %v after skipping, want %v", after.Turn, before.Turn+1)
+	}
+	if !reflect.DeepEqual(after.Resources, before.Resources) || !reflect.DeepEqual(after.Powers, before.Powers) {
+		t.Errorf("got world %+v after skipping, want %+v", after, before)
+	}
+}
+
+func TestRuleStats(t *testing.T) {
+	scenario := taxScenario(t)
+	coup := Decision{Description: "Coup", Choices: []Choice{{Description: "Stage"}}}
+	scenario.Rules = append(scenario.Rules, mustRule(t, "coup", "true", 1, coup))
+	unnamed := Decision{Description: "Census", Choices: []Choice{{Description: "Count"}}}
+	scenario.Rules = append(scenario.Rules, mustRule(t, "", "true", 1, unnamed))
+	tests := []struct {
+		choices []string
+		want    map[string]int
+	}{
+		{nil, map[string]int{}},
+		{[]string{"Raise"}, map[string]int{"tax": 1}},
+		{[]string{"Raise", "Stage", "Lower", "Count"}, map[string]int{"tax": 2, "coup": 1, "rule 2": 1}},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, choice := range test.choices {
+			mustChoose(t, e, choice)
+		}
+		if got := e.RuleStats(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.choices, got, test.want)
+		}
+	}
+}
+
+func TestMaxTurns(t *testing.T) {
+	tests := []struct {
+		maxTurns int
+		turns    int
+		want     *GameResult
+	}{
+		{0, 10, nil},
+		{1, 1, &GameResult{Outcome: TurnLimit}},
+		{5, 5, &GameResult{Outcome: TurnLimit}},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxTurns: test.maxTurns})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The tax rule is always offered, so the game only ends at the limit.
+		for turn := 0; turn < 10 && len(e.Decisions()) > 0; turn++ {
+			mustChoose(t, e, "Raise")
+		}
+		if got := e.Current().Turn; got != test.turns {
+			t.Errorf("max %v: got %v turns, want %v", test.maxTurns, got, test.turns)
+		}
+		if got := e.Result(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("max %v: got result %+v, want %+v", test.maxTurns, got, test.want)
+		}
+	}
+}
+
+func TestAlwaysAvailable(t *testing.T) {
+	scenario := taxScenario(t)
+	riot := mustRule(t, "riot", "World.Resources.Money < 4000", 1, Decision{Description: "Riot", Choices: []Choice{{Description: "Suppress"}}})
+	scenario.Rules = append(scenario.Rules, riot)
+	scenario.AlwaysAvailable = []Decision{{Description: "Check Treasury", Choices: []Choice{{Description: "Check"}}}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxDecisions: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		turn int
+		want string
+	}{
+		{1, "[Tax Check Treasury]"},
+		{10, "[Riot Check Treasury]"},
+	}
+	for _, test := range tests {
+		for e.Current().Turn < test.turn {
+			if e.Current().Turn == 5 {
+				mustChoose(t, e, "Lower")
+			} else {
+				mustChoose(t, e, "Check")
+			}
+		}
+		if got := fmt.Sprint(descriptions(e.Decisions())); got != test.want {
+			t.Errorf("turn %v: got %v, want %v", test.turn, got, test.want)
+		}
+	}
+	if got := e.Current().Resources["Money"]; got != 3900 {
+		t.Errorf("got Money %v, want 3900", got)
+	}
+}
+
+func TestChooseNotOffered(t *testing.T) {
+	scenario := taxScenario(t)
+	scenario.AlwaysAvailable = []Decision{{Description: "Act", Choices: []Choice{{Description: "Accept"}}}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		choice Choice
+	}{
+		{"unknown", Choice{Description: "Abdicate"}},
+		{"not from the offer", Choice{Description: "Raise", Change: Change{Resources: map[string]Delta{"Money": {1, 1000}}}}},
+		{"always available, not from the offer", Choice{Description: "Accept", Change: Change{Resources: map[string]Delta{"Money": {1, 1000}}}}},
+	}
+	for _, test := range tests {
+		err := e.Choose(test.choice)
+		if want := fmt.Sprintf("choice %v is not offered", test.choice.Description); err == nil || err.Error() != want {
+			t.Errorf("%v: got error %v, want %v", test.name, err, want)
+		}
+	}
+	if world := e.Current(); world.Turn != 0 || world.Resources["Money"] != 4000 {
+		t.Errorf("got turn %v and Money %v, want the initial world", world.Turn, world.Resources["Money"])
+	}
+}
+
+func TestChooseAppliesOffered(t *testing.T) {
+	scenario := taxScenario(t)
+	scenario.AlwaysAvailable = []Decision{{Description: "Act", Choices: []Choice{{
+		Description: "Accept",
+		Change:      Change{Resources: map[string]Delta{"Money": {1, 1}}},
+	}}}}
+	tests := []struct {
+		choice string
+		money  int
+	}{
+		{"Raise", 4100},
+		{"Accept", 4001},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		choice := findChoice(t, e.Decisions(), test.choice)
+		choice.Change = Change{Resources: map[string]Delta{"Money": {1, 1000}}}
+		if err := e.Choose(choice); err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Current().Resources["Money"]; got != test.money {
+			t.Errorf("%v with a changed Change: got Money %v, want %v", test.choice, got, test.money)
+		}
+	}
+}
+
+func TestChooseRollsBack(t *testing.T) {
+	// The guard of "Broke" fails to evaluate once Money drops to 4500 or
+	// less, when the decisions of the next turn are offered.
+	broke, err := NewGuardWithOptions("Money > 4500 or Money", GuardOptions{FlatNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend := Decision{Description: "Spend", Choices: []Choice{
+		{Description: "Some", Change: Change{Resources: map[string]Delta{"Money": {1, -100}}}},
+		{Description: "Lots", Change: Change{Resources: map[string]Delta{"Money": {1, -1000}}}},
+	}}
+	scenario := Scenario{
+		Rules: []Rule{
+			mustRule(t, "spend", "true", 1, spend),
+			{Name: "broke", Guard: broke, Weight: 0.5, Decision: Decision{Description: "Broke", Choices: []Choice{{Description: "Accept"}}}},
+		},
+		InitialWorld: &World{Resources: map[string]int{"Money": 5000}},
+		Decay:        map[string]Delta{"Money": {1, 1}},
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 2, MaxEvents: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Some")
+	before, decisions, events := e.Current(), descriptions(e.Decisions()), len(e.Events())
+	draws, history := e.src.draws, len(e.history)
+	err = e.Choose(findChoice(t, e.Decisions(), "Lots"))
+	if err == nil {
+		t.Fatal("got no error for a guard failing to evaluate")
+	}
+	if got := e.Current(); !reflect.DeepEqual(withoutPast(got), withoutPast(before)) {
+		t.Errorf("got world %+v after a failed choice, want %+v", got, before)
+	}
+	if got := descriptions(e.Decisions()); fmt.Sprint(got) != fmt.Sprint(decisions) {
+		t.Errorf("got decisions %v after a failed choice, want %v", got, decisions)
+	}
+	if got := len(e.state.Chosen); got != 1 {
+		t.Errorf("got %v choices recorded, want 1", got)
+	}
+	if _, ok := e.SnapshotAt(2); ok {
+		t.Errorf("got a snapshot of turn 2 after a failed choice")
+	}
+	if e.src.draws != draws || len(e.history) != history || len(e.Events()) != events {
+		t.Errorf("got %v draws, %v undo steps and %v events, want %v, %v and %v", e.src.draws, len(e.history), len(e.Events()), draws, history, events)
+	}
+	mustChoose(t, e, "Some")
+	if got := e.Current().Resources["Money"]; got != 4802 {
+		t.Errorf("got Money %v after choosing again, want 4802", got)
+	}
+}
+
+func TestChooseAtomic(t *testing.T) {
+	scenario := taxScenario(t)
+	scenario.Rules[0].Choices = append(scenario.Rules[0].Choices, Choice{
+		Description: "Gamble",
+		Change:      Change{Resources: map[string]Delta{"Money": {900, 1500, float64(OpAddRandom)}}},
+	})
+	scenario.Rules[0].ExpiresInTurns = 3
+	scenario.InitialWorld = &World{
+		Resources:    map[string]int{"Money": 4000},
+		Powers:       map[string]int{},
+		Bounds:       map[string][2]int{"Money": {0, 5000}},
+		StrictBounds: true,
+	}
+	play := func(gamble bool) *Engine {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustChoose(t, e, "Raise")
+		mustChoose(t, e, "Raise")
+		if gamble {
+			err := e.Choose(findChoice(t, e.Decisions(), "Gamble"))
+			if err == nil || !strings.HasSuffix(err.Error(), "above its upper bound 5000") {
+				t.Fatalf("got error %v, want one for the upper bound", err)
+			}
+		}
+		return e
+	}
+	e, want := play(true), play(false)
+	if !reflect.DeepEqual(withoutPast(e.Current()), withoutPast(want.Current())) {
+		t.Errorf("got world %+v after a failed choice, want %+v", e.Current(), want.Current())
+	}
+	if e.src.draws != want.src.draws {
+		t.Errorf("got %v numbers drawn after a failed choice, want %v", e.src.draws, want.src.draws)
+	}
+	if fmt.Sprint(e.pending) != fmt.Sprint(want.pending) {
+		t.Errorf("got pending decisions %v after a failed choice, want %v", e.pending, want.pending)
+	}
+	for _, money := range []int{4100, 4000} {
+		if err := e.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Current().Resources["Money"]; got != money {
+			t.Errorf("got Money %v after undoing, want %v", got, money)
+		}
+	}
+	if err := e.Undo(); err == nil {
+		t.Errorf("got more to undo than the choices made")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	tests := []struct {
+		seed  int64
+		clock bool
+	}{
+		{seed: 7},
+		{seed: 0, clock: true},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: test.seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Seed(); test.clock && got == 0 || !test.clock && got != test.seed {
+			t.Errorf("seed %v: got Seed %v", test.seed, got)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// RuleEvaluation is the outcome of evaluating a rule in the current world,
+// as reported by Engine.EvaluateAll.
+type RuleEvaluation struct {
+	// Rule is the name of the rule, or "rule <index>" if it's unnamed.
+	Rule string
+	// Available is false while the rule is on cooldown or, for Once rules,
+	// after its decision was chosen.
+	Available bool
+	Pass      bool
+	// Weight is computed from the rule's WeightExpr only if Pass is true.
+	Weight float64
+	Err    error
+}
+
+// EvaluateAll evaluates the guard and weight of every rule in the current
+// world, in order, without selecting decisions. Unlike Decisions, it
+// evaluates rules that aren't available too, and it reports errors per rule
+// instead of failing.
+func (e *Engine) EvaluateAll() []RuleEvaluation {
+	indices := make([]int, len(e.scenario.Rules))
+	for i := range indices {
+		indices[i] = i
+	}
+	evaluations := make([]RuleEvaluation, len(indices))
+	for i, ev := range e.scenario.evaluate(indices, e.world) {
+		rule := e.scenario.Rules[i]
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		evaluations[i] = RuleEvaluation{
+			Rule:      name,
+			Available: e.state.Available(i, rule, e.world.Turn),
+			Pass:      ev.pass,
+			Weight:    ev.weight,
+			Err:       ev.err,
+		}
+	}
+	return evaluations
+}
+
+// evaluation is the outcome of evaluating a rule against a world.
+type evaluation struct {
+	pass   bool
+	weight float64
+	err    error
+}
+
+// evaluate evaluates the rules at the given indices against world,
+// returning the results in the same order. If s.Parallel is set, the rules
+// are split evenly across GOMAXPROCS goroutines.
+func (s Scenario) evaluate(indices []int, world World) []evaluation {
+	evaluations := make([]evaluation, len(indices))
+	evaluateOne := func(j int) {
+		e := &evaluations[j]
+		e.pass, e.weight, e.err = s.Rules[indices[j]].evaluate(world)
+	}
+
+	if !s.Parallel {
+		for j := range indices {
+			evaluateOne(j)
+		}
+		return evaluations
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(indices) + workers - 1) / workers
+	wait := sync.WaitGroup{}
+	for start := 0; start < len(indices); start += chunk {
+		end := start + chunk
+		if end > len(indices) {
+			end = len(indices)
+		}
+		wait.Add(1)
+		go func(start, end int) {
+			defer wait.Done()
+			for j := start; j < end; j++ {
+				evaluateOne(j)
+			}
+		}(start, end)
+	}
+	wait.Wait()
+	return evaluations
+}
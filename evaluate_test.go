@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// largeScenario returns a scenario with n rules whose guards pass for even
+// rules only when Money exceeds 1000.
+func largeScenario(t testing.TB, n int, parallel bool) Scenario {
+	scenario := Scenario{Parallel: parallel}
+	for i := 0; i < n; i++ {
+		guard := "World.Resources.Money > 1000 and World.Powers.Military >= 10"
+		if i%2 == 1 {
+			guard = "World.Resources.Money < 1000"
+		}
+		decision := Decision{
+			Description: fmt.Sprintf("Decision %d", i),
+			Choices:     []Choice{{Description: "Accept"}},
+		}
+		rule, err := NewRule(fmt.Sprintf("rule%d", i), guard, float64(i%10+1)/10, decision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scenario.Rules = append(scenario.Rules, rule)
+	}
+	return scenario
+}
+
+func TestParallelDecisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		world World
+	}{
+		{"even rules pass", World{Resources: map[string]int{"Money": 2000}, Powers: map[string]int{"Military": 10}}},
+		{"odd rules pass", World{Resources: map[string]int{"Money": 500}, Powers: map[string]int{"Military": 10}}},
+		{"no rule passes", World{Resources: map[string]int{"Money": 1000}, Powers: map[string]int{"Military": 10}}},
+	}
+	sequential := largeScenario(t, 1000, false)
+	parallel := largeScenario(t, 1000, true)
+	for _, test := range tests {
+		want, err := sequential.Decisions(fixedRand(0), NewRuleState())(test.world, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parallel.Decisions(fixedRand(0), NewRuleState())(test.world, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(descriptions(got)) != fmt.Sprint(descriptions(want)) {
+			t.Errorf("%v: got %v in parallel, want %v", test.name, descriptions(got), descriptions(want))
+		}
+		if test.name == "no rule passes" && len(got) != 0 {
+			t.Errorf("%v: got %v, want no decisions", test.name, descriptions(got))
+		}
+	}
+}
+
+func BenchmarkDecisions(b *testing.B) {
+	world := World{Resources: map[string]int{"Money": 2000}, Powers: map[string]int{"Military": 10}}
+	for _, parallel := range []bool{false, true} {
+		name := "sequential"
+		if parallel {
+			name = "parallel"
+		}
+		b.Run(name, func(b *testing.B) {
+			decisions := largeScenario(b, 1000, parallel).Decisions(fixedRand(0), NewRuleState())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := decisions(world, 3); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateAll(t *testing.T) {
+	decision := func(name string) Decision {
+		return Decision{Description: name, Choices: []Choice{{Description: "Accept"}}}
+	}
+	weighted := mustRule(t, "weighted", "true", 1, decision("Weighted"))
+	weight, err := NewNumberExpr("World.Resources.Money / 10000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weighted.WeightExpr = weight
+	// The guard of the putsch fails to evaluate once Money drops, by which
+	// time the rule isn't available any more.
+	guard, err := NewGuardWithOptions("Money > 4500 or Money", GuardOptions{FlatNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putsch := Rule{Guard: guard, Weight: 1, Once: true, Decision: Decision{Description: "Putsch", Choices: []Choice{
+		{Description: "Spend", Change: Change{Resources: map[string]Delta{"Money": {1, -2000}}}},
+	}}}
+	scenario := Scenario{
+		Rules: []Rule{
+			putsch,
+			mustRule(t, "rich", "World.Resources.Money > 5000", 0.3, decision("Rich")),
+			weighted,
+		},
+		InitialWorld: &World{Resources: map[string]int{"Money": 5000}},
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxDecisions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Spend")
+	want := []string{
+		"rule 0: available false, pass false, weight 0, error true",
+		"rich: available true, pass false, weight 0.3, error false",
+		"weighted: available true, pass true, weight 0.3, error false",
+	}
+	got := make([]string, 0)
+	for _, ev := range e.EvaluateAll() {
+		got = append(got, fmt.Sprintf("%v: available %v, pass %v, weight %v, error %v", ev.Rule, ev.Available, ev.Pass, ev.Weight, ev.Err != nil))
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import "fmt"
+
+type EventKind int
+
+const (
+	// WorldInitialized is emitted when a game starts or is resumed.
+	WorldInitialized EventKind = iota + 1
+	// DecisionsOffered is emitted when decisions are offered for a turn.
+	DecisionsOffered
+	// ChoiceApplied is emitted after a choice changes the world.
+	ChoiceApplied
+	// RuleSkippedByCooldown is emitted for each rule left out of a turn
+	// because it's cooling down or has already fired once.
+	RuleSkippedByCooldown
+	// GameEnded is emitted when the game is over.
+	GameEnded
+	// DecisionsExpired is emitted when Engine.Tick withdraws expired
+	// decisions.
+	DecisionsExpired
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case WorldInitialized:
+		return "WorldInitialized"
+	case DecisionsOffered:
+		return "DecisionsOffered"
+	case ChoiceApplied:
+		return "ChoiceApplied"
+	case RuleSkippedByCooldown:
+		return "RuleSkippedByCooldown"
+	case GameEnded:
+		return "GameEnded"
+	case DecisionsExpired:
+		return "DecisionsExpired"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event records something that happened during a game. Only the fields
+// relevant to its Kind are set.
+type Event struct {
+	Kind EventKind
+	Turn int
+	// World is the world after the event.
+	World World
+	// Before is the world before a ChoiceApplied event.
+	Before    World
+	Decisions []Decision
+	// Trace explains how the decisions of a DecisionsOffered event were
+	// selected, unless they are follow-up decisions.
+	Trace  *DecisionTrace
+	Choice *Choice
+	// Rule is the name of a skipped rule.
+	Rule string
+	// Result is how the game ended, or nil if it got stuck.
+	Result *GameResult
+}
+
+// Events returns the last GameConfig.MaxEvents events emitted, in order.
+func (e *Engine) Events() []Event {
+	return e.events
+}
+
+type handler struct {
+	id int
+	fn func(Event)
+}
+
+// OnEvent registers fn to be called with each event as it's emitted, until
+// the returned function is called to unregister it.
+func (e *Engine) OnEvent(fn func(Event)) (unregister func()) {
+	e.lastSubscriber++
+	id := e.lastSubscriber
+	e.handlers = append(e.handlers, handler{id, fn})
+	return func() {
+		for i, h := range e.handlers {
+			if h.id == id {
+				e.handlers = append(e.handlers[:i:i], e.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (e *Engine) emit(event Event) {
+	// Copying the world is costly, so it's only done if the event is
+	// logged or handled.
+	if len(e.handlers) > 0 || e.cfg.MaxEvents > 0 {
+		event.Turn = e.world.Turn
+		event.World = e.world.Copy()
+	}
+	if max := e.cfg.MaxEvents; max > 0 {
+		if len(e.events) == max {
+			e.events = e.events[1:]
+		}
+		e.events = append(e.events, event)
+	}
+	for _, h := range e.handlers {
+		h.fn(event)
+	}
+	if event.Kind == DecisionsOffered || event.Kind == GameEnded || event.Kind == DecisionsExpired {
+		e.notify()
+	}
+}
+
+type subscriber struct {
+	id int
+	fn func(World, []Decision)
+}
+
+// Subscribe registers fn to be called with a copy of the world and the
+// offered decisions right away and then whenever they change, until the
+// returned function is called to unsubscribe. fn is called on the
+// goroutine calling the engine's methods and must not call Choose or Undo.
+func (e *Engine) Subscribe(fn func(World, []Decision)) (unsubscribe func()) {
+	e.lastSubscriber++
+	id := e.lastSubscriber
+	e.subscribers = append(e.subscribers, subscriber{id, fn})
+	fn(e.world.Copy(), e.decisions)
+	return func() {
+		for i, s := range e.subscribers {
+			if s.id == id {
+				e.subscribers = append(e.subscribers[:i:i], e.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (e *Engine) notify() {
+	for _, s := range e.subscribers {
+		s.fn(e.world.Copy(), e.decisions)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// mustChoose picks the choice described as description among those offered by
+// e.
+func mustChoose(t *testing.T, e *Engine, description string) {
+	t.Helper()
+	for _, decision := range e.Decisions() {
+		for _, choice := range decision.Choices {
+			if choice.Description == description {
+				if err := e.Choose(choice); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+		}
+	}
+	t.Fatalf("choice %q not offered in %v", description, descriptions(e.Decisions()))
+}
+
+func TestEngineEvents(t *testing.T) {
+	scenario := taxScenario(t)
+	coup := mustRule(t, "coup", "true", 1, Decision{Description: "Coup", Choices: []Choice{{Description: "Stage"}}})
+	coup.Once = true
+	scenario.Rules = append(scenario.Rules, coup)
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxEvents: 100, WinConditions: []string{"World.Resources.Money >= 4200"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled []Event
+	e.OnEvent(func(event Event) {
+		handled = append(handled, event)
+	})
+	mustChoose(t, e, "Stage")
+	mustChoose(t, e, "Raise")
+	mustChoose(t, e, "Raise")
+
+	tests := []struct {
+		kind   EventKind
+		turn   int
+		money  int
+		detail string
+		before int
+	}{
+		{WorldInitialized, 0, 4000, "", 0},
+		{DecisionsOffered, 0, 4000, "[Coup Tax]", 0},
+		{ChoiceApplied, 1, 4000, "Stage", 4000},
+		{RuleSkippedByCooldown, 1, 4000, "coup", 0},
+		{DecisionsOffered, 1, 4000, "[Tax]", 0},
+		{ChoiceApplied, 2, 4100, "Raise", 4000},
+		{RuleSkippedByCooldown, 2, 4100, "coup", 0},
+		{DecisionsOffered, 2, 4100, "[Tax]", 0},
+		{ChoiceApplied, 3, 4200, "Raise", 4100},
+		{GameEnded, 3, 4200, "win", 0},
+	}
+	events := e.Events()
+	if len(events) != len(tests) {
+		t.Fatalf("got %v events, want %v", len(events), len(tests))
+	}
+	for i, test := range tests {
+		event := events[i]
+		if event.Kind != test.kind || event.Turn != test.turn || event.World.Resources["Money"] != test.money {
+			t.Errorf("event %d: got %v on turn %v with Money %v, want %v on turn %v with Money %v",
+				i, event.Kind, event.Turn, event.World.Resources["Money"], test.kind, test.turn, test.money)
+		}
+		var detail string
+		switch event.Kind {
+		case DecisionsOffered:
+			detail = fmt.Sprint(descriptions(event.Decisions))
+		case ChoiceApplied:
+			detail = event.Choice.Description
+			if got := event.Before.Resources["Money"]; got != test.before {
+				t.Errorf("event %d: got Money %v before, want %v", i, got, test.before)
+			}
+		case RuleSkippedByCooldown:
+			detail = event.Rule
+		case GameEnded:
+			detail = event.Result.Outcome.String()
+		}
+		if detail != test.detail {
+			t.Errorf("event %d: got %v, want %v", i, detail, test.detail)
+		}
+	}
+	// The handler was registered after the first two events.
+	if len(handled) != len(events)-2 {
+		t.Errorf("got %v events handled, want %v", len(handled), len(events)-2)
+	}
+}
+
+func TestEngineSubscribe(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxUndo: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updates []string
+	unsubscribe := e.Subscribe(func(world World, decisions []Decision) {
+		updates = append(updates, fmt.Sprintf("%v %v", world.Resources["Money"], descriptions(decisions)))
+	})
+	tests := []struct {
+		action func() error
+		want   []string
+	}{
+		{func() error { return nil }, []string{"4000 [Tax]"}},
+		{func() error { return e.Choose(e.Decisions()[0].Choices[0]) }, []string{"4000 [Tax]", "4100 [Tax]"}},
+		{func() error { return e.Choose(e.Decisions()[0].Choices[1]) }, []string{"4000 [Tax]", "4100 [Tax]", "4000 [Tax]"}},
+		{e.Undo, []string{"4000 [Tax]", "4100 [Tax]", "4000 [Tax]", "4100 [Tax]"}},
+	}
+	for i, test := range tests {
+		if err := test.action(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(updates) != fmt.Sprint(test.want) {
+			t.Errorf("step %v: got updates %v, want %v", i, updates, test.want)
+		}
+	}
+
+	unsubscribe()
+	n := len(updates)
+	mustChoose(t, e, "Raise")
+	if len(updates) != n {
+		t.Errorf("got %v updates after unsubscribing", len(updates)-n)
+	}
+}
+
+func TestMaxEvents(t *testing.T) {
+	tests := []struct {
+		max  int
+		want string
+	}{
+		{0, "[]"},
+		{3, "[DecisionsOffered ChoiceApplied DecisionsOffered]"},
+		{100, "[WorldInitialized DecisionsOffered ChoiceApplied DecisionsOffered ChoiceApplied DecisionsOffered]"},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxEvents: test.max})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handled := 0
+		e.OnEvent(func(Event) { handled++ })
+		mustChoose(t, e, "Raise")
+		mustChoose(t, e, "Lower")
+		var kinds []EventKind
+		for _, event := range e.Events() {
+			kinds = append(kinds, event.Kind)
+		}
+		if got := fmt.Sprint(kinds); got != test.want {
+			t.Errorf("max %v: got events %v, want %v", test.max, got, test.want)
+		}
+		if handled != 4 {
+			t.Errorf("max %v: got %v events handled, want 4", test.max, handled)
+		}
+	}
+}
+
+func TestOnEventUnregister(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled []string
+	register := func(name string) func() {
+		return e.OnEvent(func(event Event) {
+			if event.Kind == ChoiceApplied {
+				handled = append(handled, name)
+			}
+		})
+	}
+	unregisterA := register("a")
+	unregisterB := register("b")
+	var unregisterSelf func()
+	unregisterSelf = e.OnEvent(func(event Event) {
+		if event.Kind == ChoiceApplied {
+			handled = append(handled, "self")
+			unregisterSelf()
+		}
+	})
+	register("c")
+
+	tests := []struct {
+		unregister func()
+		want       string
+	}{
+		{nil, "[a b self c]"},
+		{unregisterB, "[a c]"},
+		{unregisterB, "[a c]"},
+		{unregisterA, "[c]"},
+	}
+	for i, test := range tests {
+		if test.unregister != nil {
+			test.unregister()
+		}
+		handled = nil
+		mustChoose(t, e, "Raise")
+		if got := fmt.Sprint(handled); got != test.want {
+			t.Errorf("step %v: got handlers %v called, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestEmitUnobserved(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxEvents int
+		handled   bool
+		allocs    bool
+	}{
+		{name: "unobserved"},
+		{name: "logged", maxEvents: 1, allocs: true},
+		{name: "handled", handled: true, allocs: true},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxEvents: test.maxEvents})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.handled {
+			e.OnEvent(func(Event) {})
+		}
+		allocs := testing.AllocsPerRun(10, func() {
+			e.emit(Event{Kind: RuleSkippedByCooldown, Rule: "tax"})
+		})
+		if got := allocs > 0; got != test.allocs {
+			t.Errorf("%v: got %v allocations per event", test.name, allocs)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+// pendingDecision is an offered decision with ExpiresInTurns, kept on offer
+// in the following turns until it's chosen or expires.
+type pendingDecision struct {
+	Decision
+	// turn is the turn the decision was first offered on.
+	turn int
+}
+
+func (p pendingDecision) expired(turn int) bool {
+	return turn >= p.turn+p.ExpiresInTurns
+}
+
+func sameDecision(a, b Decision) bool {
+	return a.Rule == b.Rule && a.Description == b.Description
+}
+
+// expirePending withdraws the pending decisions that expired by the current
+// turn. As this happens when the turn starts, the changes their defaults
+// make add up with those of the choice that ended the previous turn in
+// World.LastChange.
+func (e *Engine) expirePending() error {
+	var pending []pendingDecision
+	var expired []Decision
+	for _, p := range e.pending {
+		if p.expired(e.world.Turn) {
+			expired = append(expired, p.Decision)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+	e.pending = pending
+	changed := e.world.LastChange
+	applied, err := e.expire(expired)
+	if err != nil || !applied {
+		return err
+	}
+	for key, delta := range changed {
+		e.world.LastChange[key] += delta
+	}
+	return nil
+}
+
+// expire reports that decisions expired and applies their defaults, each
+// to a copy of the world swapped in once it succeeds, like Choose does.
+// It reports whether any default was applied, making World.LastChange the
+// change of all of them. Callers roll back on error.
+func (e *Engine) expire(decisions []Decision) (applied bool, err error) {
+	if len(decisions) == 0 {
+		return false, nil
+	}
+	e.emit(Event{Kind: DecisionsExpired, Decisions: decisions})
+	start := e.world
+	for _, decision := range decisions {
+		if decision.Default == nil {
+			continue
+		}
+		choice := *decision.Default
+		world := e.world.Copy()
+		if err := world.Apply(choice, e.rand); err != nil {
+			return false, err
+		}
+		before := e.world
+		e.world = world
+		applied = true
+		e.emit(Event{Kind: ChoiceApplied, Before: before, Choice: &choice})
+	}
+	if applied {
+		e.world.recordChange(start)
+	}
+	return applied, nil
+}
+
+// withPending returns the pending decisions followed by decisions, leaving
+// out those already pending and keeping at most MaxDecisions. Those of
+// decisions expiring in turns become pending.
+func (e *Engine) withPending(decisions []Decision) []Decision {
+	all := make([]Decision, 0, len(e.pending)+len(decisions))
+	for _, p := range e.pending {
+		all = append(all, p.Decision)
+	}
+	for _, decision := range decisions {
+		if len(all) >= e.cfg.MaxDecisions {
+			break
+		}
+		if e.isPending(decision) {
+			continue
+		}
+		if decision.ExpiresInTurns > 0 {
+			e.pending = append(e.pending, pendingDecision{decision, e.world.Turn})
+		}
+		all = append(all, decision)
+	}
+	return all
+}
+
+func (e *Engine) isPending(decision Decision) bool {
+	for _, p := range e.pending {
+		if sameDecision(p.Decision, decision) {
+			return true
+		}
+	}
+	return false
+}
+
+// settle stops keeping decision on offer.
+func (e *Engine) settle(decision Decision) {
+	pending := make([]pendingDecision, 0, len(e.pending))
+	for _, p := range e.pending {
+		if !sameDecision(p.Decision, decision) {
+			pending = append(pending, p)
+		}
+	}
+	e.pending = pending
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpiresInTurns(t *testing.T) {
+	ultimatum := Decision{
+		Description:    "Ultimatum",
+		Choices:        []Choice{{Description: "Yield", Change: Change{Resources: map[string]Delta{"Money": {1, -100}}}}},
+		ExpiresInTurns: 2,
+		Default:        &Choice{Description: "War", Change: Change{Resources: map[string]Delta{"Money": {1, -500}}}},
+	}
+	scenario := taxScenario(t)
+	scenario.Rules = append(scenario.Rules, mustRule(t, "ultimatum", "World.Turn == 1", 1, ultimatum))
+	tests := []struct {
+		name    string
+		choices []string
+		// decisions are those offered each turn after the choices.
+		decisions []string
+		money     int
+	}{
+		{
+			name:      "expired",
+			choices:   []string{"Raise", "Raise", "Raise", "Raise"},
+			decisions: []string{"[Tax Ultimatum]", "[Ultimatum Tax]", "[Tax]", "[Tax]"},
+			money:     4400 - 500,
+		},
+		{
+			name:      "chosen",
+			choices:   []string{"Raise", "Yield", "Raise", "Raise"},
+			decisions: []string{"[Tax Ultimatum]", "[Tax]", "[Tax]", "[Tax]"},
+			money:     4300 - 100,
+		},
+	}
+	for _, test := range tests {
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expired []string
+		e.OnEvent(func(event Event) {
+			if event.Kind == DecisionsExpired {
+				expired = append(expired, fmt.Sprint(descriptions(event.Decisions)))
+			}
+		})
+		for turn, choice := range test.choices {
+			mustChoose(t, e, choice)
+			if got := descriptions(e.Decisions()); fmt.Sprint(got) != test.decisions[turn] {
+				t.Errorf("%v, turn %v: got %v, want %v", test.name, turn+1, got, test.decisions[turn])
+			}
+		}
+		if got := e.Current().Resources["Money"]; got != test.money {
+			t.Errorf("%v: got Money %v, want %v", test.name, got, test.money)
+		}
+		if test.name == "expired" && fmt.Sprint(expired) != "[[Ultimatum]]" {
+			t.Errorf("%v: got expired %v, want [[Ultimatum]]", test.name, expired)
+		}
+	}
+}
+
+func TestExpiresInTurnsUndo(t *testing.T) {
+	ultimatum := Decision{
+		Description:    "Ultimatum",
+		Choices:        []Choice{{Description: "Yield"}},
+		ExpiresInTurns: 1,
+	}
+	scenario := taxScenario(t)
+	scenario.Rules = append(scenario.Rules, mustRule(t, "ultimatum", "World.Turn == 0", 1, ultimatum))
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Yield")
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Raise")
+	if got := descriptions(e.Decisions()); fmt.Sprint(got) != "[Tax]" {
+		t.Errorf("got %v after undoing a choice, want the ultimatum expired", got)
+	}
+}
+
+func TestExpiresInTurnsLastChange(t *testing.T) {
+	ultimatum := Decision{
+		Description:    "Ultimatum",
+		Choices:        []Choice{{Description: "Yield"}},
+		ExpiresInTurns: 1,
+		Default:        &Choice{Description: "War", Change: Change{Resources: map[string]Delta{"Money": {-500, 0, float64(OpAdd)}}}},
+	}
+	scenario := taxScenario(t)
+	scenario.Rules = append(scenario.Rules, mustRule(t, "ultimatum", "World.Turn == 0", 1, ultimatum))
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Raise")
+	// The default applied as the turn starts adds up with the choice.
+	if got := e.Current().LastChange["Money"]; got != 100-500 {
+		t.Errorf("got LastChange %v, want %v", got, 100-500)
+	}
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Current().Resources["Money"]; got != 4000 {
+		t.Errorf("got Money %v after Undo, want 4000", got)
+	}
+}
+
+func TestDecisionFileExpiry(t *testing.T) {
+	data := `{"rules": [{"name": "ultimatum", "guard": "true", "weight": 1, "decision": {
+		"description": "Ultimatum",
+		"choices": [{"description": "Yield"}],
+		"expiresInTurns": 2,
+		"default": {"description": "War", "change": {"resources": {"Money": [1, -500]}}}
+	}}]}`
+	var file scenarioFile
+	if err := json.Unmarshal([]byte(data), &file); err != nil {
+		t.Fatal(err)
+	}
+	scenario, err := file.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decision := scenario.Rules[0].Decision
+	want := &Choice{Description: "War", Change: Change{Resources: map[string]Delta{"Money": {1, -500}}}}
+	if decision.ExpiresInTurns != 2 || !reflect.DeepEqual(decision.Default, want) {
+		t.Errorf("got expiry %v and default %+v, want 2 and %+v", decision.ExpiresInTurns, decision.Default, want)
+	}
+	if round := newDecisionFile(decision); round.ExpiresInTurns != 2 || round.Default == nil || round.Default.Description != "War" {
+		t.Errorf("got %+v after a round trip", round)
+	}
+
+	decision.ExpiresInTurns = -1
+	err = Scenario{Rules: []Rule{mustRule(t, "ultimatum", "true", 1, decision)}}.Validate()
+	if want := `rule 0 (ultimatum): decision "Ultimatum" expires in negative turns -1`; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
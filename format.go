@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// FormatKind selects how a resource or power value is displayed.
+type FormatKind int
+
+const (
+	// Integer displays the value as is, e.g. "4000".
+	Integer FormatKind = iota
+	// Currency displays the value in dollars with grouped thousands, e.g.
+	// "$4,000".
+	Currency
+	// Percent displays the value followed by a percent sign, e.g. "90%".
+	Percent
+)
+
+func (k FormatKind) String() string {
+	switch k {
+	case Integer:
+		return "integer"
+	case Currency:
+		return "currency"
+	case Percent:
+		return "percent"
+	default:
+		return fmt.Sprintf("FormatKind(%d)", int(k))
+	}
+}
+
+func (k FormatKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *FormatKind) UnmarshalText(text []byte) error {
+	for _, kind := range []FormatKind{Integer, Currency, Percent} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q", text)
+}
+
+// ResourceMeta maps resources and powers to how their values are displayed.
+// Keys without an entry are displayed as integers.
+type ResourceMeta map[string]FormatKind
+
+// FormatValue formats the value of the resource or power key for display.
+func FormatValue(key string, value int, meta ResourceMeta) string {
+	switch meta[key] {
+	case Currency:
+		if value < 0 {
+			return "-$" + groupThousands(-value)
+		}
+		return "$" + groupThousands(value)
+	case Percent:
+		return strconv.Itoa(value) + "%"
+	default:
+		return strconv.Itoa(value)
+	}
+}
+
+// FormatReal formats the value of the fractional resource key for display.
+// Currencies are shown with cents, e.g. "$1,234.50".
+func FormatReal(key string, value float64, meta ResourceMeta) string {
+	switch meta[key] {
+	case Currency:
+		cents := int(math.Round(math.Abs(value) * 100))
+		text := fmt.Sprintf("$%v.%02d", groupThousands(cents/100), cents%100)
+		if value < 0 && cents > 0 {
+			return "-" + text
+		}
+		return text
+	case Percent:
+		return strconv.FormatFloat(value, 'f', -1, 64) + "%"
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+// groupThousands formats a non-negative value with commas between groups
+// of three digits.
+func groupThousands(value int) string {
+	digits := strconv.Itoa(value)
+	out := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, digits[i])
+	}
+	return string(out)
+}
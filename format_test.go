@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestFormatValue(t *testing.T) {
+	meta := ResourceMeta{"Money": Currency, "Military": Percent, "Population": Integer}
+	tests := []struct {
+		key   string
+		value int
+		want  string
+	}{
+		{"Money", 0, "$0"},
+		{"Money", 999, "$999"},
+		{"Money", 4000, "$4,000"},
+		{"Money", 123456, "$123,456"},
+		{"Money", 1234567, "$1,234,567"},
+		{"Money", -4000, "-$4,000"},
+		{"Money", -12, "-$12"},
+		{"Military", 90, "90%"},
+		{"Military", 0, "0%"},
+		{"Military", -5, "-5%"},
+		{"Population", 4000, "4000"},
+		{"Legislation", -10, "-10"},
+	}
+	for _, test := range tests {
+		if got := FormatValue(test.key, test.value, meta); got != test.want {
+			t.Errorf("%v %v: got %q, want %q", test.key, test.value, got, test.want)
+		}
+	}
+	if got := FormatValue("Money", 4000, nil); got != "4000" {
+		t.Errorf("got %q without metadata, want 4000", got)
+	}
+}
+
+func TestFormatReal(t *testing.T) {
+	meta := ResourceMeta{"Money": Currency, "Approval": Percent}
+	tests := []struct {
+		key   string
+		value float64
+		want  string
+	}{
+		{"Money", 1234.5, "$1,234.50"},
+		{"Money", 0.004, "$0.00"},
+		{"Money", -0.004, "$0.00"},
+		{"Money", -1234.567, "-$1,234.57"},
+		{"Approval", 62.5, "62.5%"},
+		{"Approval", 100, "100%"},
+		{"Debt", -0.25, "-0.25"},
+	}
+	for _, test := range tests {
+		if got := FormatReal(test.key, test.value, meta); got != test.want {
+			t.Errorf("%v %v: got %q, want %q", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestFormatKindText(t *testing.T) {
+	for _, kind := range []FormatKind{Integer, Currency, Percent} {
+		text, err := kind.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got FormatKind
+		if err := got.UnmarshalText(text); err != nil || got != kind {
+			t.Errorf("%v: got %v, %v after a round trip", kind, got, err)
+		}
+	}
+	var kind FormatKind
+	if err := kind.UnmarshalText([]byte("euro")); err == nil {
+		t.Errorf("got no error for an unknown format")
+	}
+}
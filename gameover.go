@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gameOverText is the text of the console UI's end screen: why the game
+// ended, given its result or nil if it ended for lack of decisions, and the
+// final world.
+func gameOverText(result *GameResult, world World, meta ResourceMeta) string {
+	var lines []string
+	switch {
+	case result == nil:
+		lines = append(lines, "No decisions left.")
+	case result.Outcome == Win:
+		lines = append(lines, fmt.Sprintf("You won: %v", result.Condition))
+	case result.Outcome == Lose:
+		lines = append(lines, fmt.Sprintf("You lost: %v", result.Condition))
+	case result.Outcome == TurnLimit:
+		lines = append(lines, "Turn limit reached.")
+	default:
+		lines = append(lines, fmt.Sprintf("Game ended (%v): %v", result.Outcome, result.Condition))
+	}
+
+	lines = append(lines, "")
+	for _, key := range sortedIntKeys(world.Resources) {
+		lines = append(lines, fmt.Sprintf("%v: %v", key, FormatValue(key, world.Resources[key], meta)))
+	}
+	for _, key := range sortedFloatKeys(world.Reals) {
+		lines = append(lines, fmt.Sprintf("%v: %v", key, FormatReal(key, world.Reals[key], meta)))
+	}
+	for _, key := range sortedIntKeys(world.Powers) {
+		lines = append(lines, fmt.Sprintf("%v: %v", key, FormatValue(key, world.Powers[key], meta)))
+	}
+	lines = append(lines, "", fmt.Sprintf("Turns played: %v", world.Turn))
+	return strings.Join(lines, "\n")
+}
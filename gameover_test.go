@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestGameOverText(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": -200, "Food": 30},
+		Reals:     map[string]float64{"Approval": 12.5},
+		Powers:    map[string]int{"Military": 40},
+		Turn:      7,
+	}
+	meta := ResourceMeta{"Money": Currency, "Military": Percent}
+	const summary = "\n\nFood: 30\nMoney: -$200\nApproval: 12.5\nMilitary: 40%\n\nTurns played: 7"
+	tests := []struct {
+		name   string
+		result *GameResult
+		want   string
+	}{
+		{"stuck", nil, "No decisions left." + summary},
+		{"win", &GameResult{Outcome: Win, Condition: "World.Powers.Military >= 90"}, "You won: World.Powers.Military >= 90" + summary},
+		{"lose", &GameResult{Outcome: Lose, Condition: "World.Resources.Money <= 0"}, "You lost: World.Resources.Money <= 0" + summary},
+		{"turn limit", &GameResult{Outcome: TurnLimit}, "Turn limit reached." + summary},
+	}
+	for _, test := range tests {
+		if got := gameOverText(test.result, world, meta); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+	if got, want := gameOverText(nil, World{}, nil), "No decisions left.\n\n\nTurns played: 0"; got != want {
+		t.Errorf("got %q for an empty world, want %q", got, want)
+	}
+}
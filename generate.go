@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// RandomScenario generates a valid scenario with numRules rules over the
+// given keys, e.g. to fuzz the engine. Each key randomly becomes a resource
+// or a power of the initial world, and rules have random guards comparing
+// them, weights and decisions changing them. The same arguments always
+// generate the same scenario.
+func RandomScenario(seed int64, numRules int, keys []string) Scenario {
+	r := rand.New(rand.NewSource(seed))
+	initial := World{Resources: make(map[string]int), Powers: make(map[string]int)}
+	isPower := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if r.Intn(2) == 0 {
+			isPower[key] = true
+			initial.Powers[key] = r.Intn(101)
+		} else {
+			initial.Resources[key] = r.Intn(5001)
+		}
+	}
+	g := generator{r: r, keys: keys, isPower: isPower}
+
+	rules := make([]Rule, numRules)
+	for i := range rules {
+		guard := g.guard()
+		rule, err := NewRule(fmt.Sprintf("rule%d", i), guard, r.Float64(), g.decision(i))
+		if err != nil {
+			panic(fmt.Sprintf("invalid generated guard %q: %v", guard, err))
+		}
+		rules[i] = rule
+	}
+	return Scenario{Rules: rules, InitialWorld: &initial}
+}
+
+type generator struct {
+	r       *rand.Rand
+	keys    []string
+	isPower map[string]bool
+}
+
+func (g generator) key() string {
+	return g.keys[g.r.Intn(len(g.keys))]
+}
+
+// guard returns a guard of up to three comparisons of keys with numbers.
+func (g generator) guard() string {
+	if len(g.keys) == 0 {
+		return "true"
+	}
+	n := 1 + g.r.Intn(3)
+	comparisons := make([]string, n)
+	for i := range comparisons {
+		key := g.key()
+		fn, max := "res", 5000
+		if g.isPower[key] {
+			fn, max = "pow", 100
+		}
+		op := []string{"<", "<=", ">", ">="}[g.r.Intn(4)]
+		comparisons[i] = fmt.Sprintf("%v(%q, 0) %v %v", fn, key, op, g.r.Intn(max+1))
+	}
+	joiner := []string{" and ", " or "}[g.r.Intn(2)]
+	return strings.Join(comparisons, joiner)
+}
+
+func (g generator) decision(i int) Decision {
+	choices := make([]Choice, 1+g.r.Intn(3))
+	for j := range choices {
+		choices[j] = Choice{
+			Description: fmt.Sprintf("Choice %d.%d", i, j),
+			Change:      g.change(),
+		}
+	}
+	return Decision{Description: fmt.Sprintf("Decision %d", i), Choices: choices}
+}
+
+// change returns a change of up to two keys.
+func (g generator) change() Change {
+	var change Change
+	if len(g.keys) == 0 {
+		return change
+	}
+	for n := g.r.Intn(3); n > 0; n-- {
+		key := g.key()
+		values := &change.Resources
+		if g.isPower[key] {
+			values = &change.Powers
+		}
+		if *values == nil {
+			*values = make(map[string]Delta)
+		}
+		(*values)[key] = g.delta()
+	}
+	return change
+}
+
+func (g generator) delta() Delta {
+	switch op := DeltaOp(g.r.Intn(int(OpAddRandom) + 1)); op {
+	case OpLinear:
+		return Delta{0.5 + g.r.Float64(), float64(g.r.Intn(201) - 100)}
+	case OpSet:
+		return Delta{float64(g.r.Intn(101)), 0, float64(op)}
+	case OpAdd:
+		return Delta{float64(g.r.Intn(201) - 100), 0, float64(op)}
+	case OpMul:
+		return Delta{0.5 + g.r.Float64(), 0, float64(op)}
+	case OpAddPercent:
+		return Delta{float64(g.r.Intn(41) - 20), 0, float64(op)}
+	default:
+		lo := float64(g.r.Intn(101) - 50)
+		return Delta{lo, lo + float64(g.r.Intn(51)), float64(OpAddRandom)}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRandomScenario(t *testing.T) {
+	tests := []struct {
+		seed     int64
+		numRules int
+		keys     []string
+	}{
+		{1, 0, nil},
+		{2, 5, nil},
+		{3, 10, []string{"Money"}},
+		{4, 20, []string{"Money", "Food", "Military", "Legislation"}},
+		{5, 50, []string{"Money", "Food", "Military", "Legislation", "Church", "Nobles"}},
+	}
+	for _, test := range tests {
+		scenario := RandomScenario(test.seed, test.numRules, test.keys)
+		if len(scenario.Rules) != test.numRules {
+			t.Errorf("seed %v: got %v rules, want %v", test.seed, len(scenario.Rules), test.numRules)
+		}
+		if err := scenario.Validate(); err != nil {
+			t.Errorf("seed %v: %v", test.seed, err)
+			continue
+		}
+		if !reflect.DeepEqual(RandomScenario(test.seed, test.numRules, test.keys), scenario) {
+			t.Errorf("seed %v: got different scenarios for the same seed", test.seed)
+		}
+		for seed := int64(1); seed <= 5; seed++ {
+			if _, err := playEngine(scenario, seed, 10); err != nil {
+				t.Errorf("seed %v, game %v: %v", test.seed, seed, err)
+			}
+		}
+	}
+}
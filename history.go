@@ -0,0 +1,87 @@
+package main
+
+// maxPastWorlds is the default number of past turns guards can look back
+// at with the history function.
+const maxPastWorlds = 32
+
+// pastWorlds holds the worlds at the start of recent turns, oldest first.
+type pastWorlds struct {
+	worlds []World
+	// max is the number of worlds kept, or maxPastWorlds if zero.
+	max int
+}
+
+func (p *pastWorlds) push(world World) {
+	world = world.Copy()
+	world.past = nil
+	world.state = nil
+	max := p.max
+	if max == 0 {
+		max = maxPastWorlds
+	}
+	if len(p.worlds) >= max {
+		p.worlds = p.worlds[len(p.worlds)-max+1:]
+	}
+	p.worlds = append(p.worlds, world)
+}
+
+func (p *pastWorlds) pop() {
+	if len(p.worlds) > 0 {
+		p.worlds = p.worlds[:len(p.worlds)-1]
+	}
+}
+
+// ago returns the world n turns ago, or the oldest one kept if there are
+// fewer, and false if there's none.
+func (p *pastWorlds) ago(n int) (World, bool) {
+	if len(p.worlds) == 0 {
+		return World{}, false
+	}
+	if n > len(p.worlds) {
+		n = len(p.worlds)
+	}
+	return p.worlds[len(p.worlds)-n], true
+}
+
+// at returns the world at the start of turn, if kept.
+func (p *pastWorlds) at(turn int) (World, bool) {
+	for _, world := range p.worlds {
+		if world.Turn == turn {
+			return world, true
+		}
+	}
+	return World{}, false
+}
+
+// keeps reports whether the newest world kept is that at the start of turn.
+func (p *pastWorlds) keeps(turn int) bool {
+	return len(p.worlds) > 0 && p.worlds[len(p.worlds)-1].Turn == turn
+}
+
+// historyFunc is the type of the history guard function: history(key, n)
+// is the value of the resource or power key n turns ago, or at the oldest
+// turn remembered if the game is younger or the turn has been forgotten.
+// Missing keys are 0.
+type historyFunc func(key string, n float64) float64
+
+func (w World) history(key string, n float64) float64 {
+	world := w
+	if n >= 1 && w.past != nil {
+		if past, ok := w.past.ago(int(n)); ok {
+			world = past
+		}
+	}
+	return world.value(key)
+}
+
+// value returns the value of the resource or power key, or 0 if it's
+// missing.
+func (w World) value(key string) float64 {
+	if v, ok := w.Resources[key]; ok {
+		return float64(v)
+	}
+	if v, ok := w.Reals[key]; ok {
+		return v
+	}
+	return float64(w.Powers[key])
+}
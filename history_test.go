@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPastWorlds(t *testing.T) {
+	var past pastWorlds
+	world := World{Resources: map[string]int{"Money": 100}}
+	if got := world.history("Money", 1); got != 100 {
+		t.Errorf("got %v without past worlds, want the current value 100", got)
+	}
+	world.past = &past
+	for money := 1; money <= maxPastWorlds+2; money++ {
+		past.push(World{Resources: map[string]int{"Money": money}})
+	}
+	tests := []struct {
+		key  string
+		n    float64
+		want float64
+	}{
+		{"Money", 0, 100},
+		{"Money", 1, maxPastWorlds + 2},
+		{"Money", 2, maxPastWorlds + 1},
+		{"Money", maxPastWorlds, 3},
+		{"Money", maxPastWorlds + 10, 3},
+		{"Gold", 1, 0},
+	}
+	for _, test := range tests {
+		if got := world.history(test.key, test.n); got != test.want {
+			t.Errorf("history(%q, %v): got %v, want %v", test.key, test.n, got, test.want)
+		}
+	}
+}
+
+func TestHistoryGuard(t *testing.T) {
+	budget := Decision{Description: "Budget", Choices: []Choice{
+		{Description: "Spend", Change: Change{Resources: map[string]Delta{"Money": {-1000, 0, float64(OpAdd)}}}},
+		{Description: "Earn", Change: Change{Resources: map[string]Delta{"Money": {1000, 0, float64(OpAdd)}}}},
+	}}
+	crisis := Decision{Description: "Crisis", Choices: []Choice{{Description: "Accept"}}}
+	streak := `World.Resources.Money < 1000 and history("Money", 1) < 1000 and history("Money", 2) < 1000`
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "budget", "true", 1, budget),
+		mustRule(t, "crisis", streak, 1, crisis),
+	}}
+	e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxUndo: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		choice string
+		want   string
+	}{
+		{"Spend", "[Budget]"},        // 3000
+		{"Spend", "[Budget]"},        // 2000
+		{"Spend", "[Budget]"},        // 1000
+		{"Spend", "[Budget]"},        // 0
+		{"Spend", "[Budget]"},        // -1000
+		{"Spend", "[Budget Crisis]"}, // -2000
+		{"Earn", "[Budget Crisis]"},  // -1000
+		{"Earn", "[Budget Crisis]"},  // 0
+		{"Earn", "[Budget]"},         // 1000
+	}
+	for turn, test := range tests {
+		mustChoose(t, e, test.choice)
+		if got := fmt.Sprint(descriptions(e.Decisions())); got != test.want {
+			t.Errorf("turn %v: got %v, want %v", turn, got, test.want)
+		}
+	}
+
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Spend")
+	if got := fmt.Sprint(descriptions(e.Decisions())); got != "[Budget Crisis]" {
+		t.Errorf("got %v after undoing, want [Budget Crisis]", got)
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1, MaxSnapshots: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, choice := range []string{"Raise", "Raise", "Lower", "Raise", "Raise"} {
+		mustChoose(t, e, choice)
+	}
+	tests := []struct {
+		turn  int
+		ok    bool
+		money int
+	}{
+		{0, false, 0},
+		{1, false, 0},
+		{2, true, 4200},
+		{3, true, 4100},
+		{4, true, 4200},
+		{5, true, 4300},
+		{6, false, 0},
+	}
+	for _, test := range tests {
+		world, ok := e.SnapshotAt(test.turn)
+		if ok != test.ok || world.Resources["Money"] != test.money {
+			t.Errorf("turn %v: got %v and Money %v, want %v and %v", test.turn, ok, world.Resources["Money"], test.ok, test.money)
+		}
+		if ok && world.Turn != test.turn {
+			t.Errorf("turn %v: got the world of turn %v", test.turn, world.Turn)
+		}
+	}
+
+	world, _ := e.SnapshotAt(2)
+	world.Resources["Money"] = 0
+	if world, _ := e.SnapshotAt(2); world.Resources["Money"] != 4200 {
+		t.Errorf("got Money %v after changing a snapshot, want 4200", world.Resources["Money"])
+	}
+	if got := e.Current().Resources["Money"]; got != 4300 {
+		t.Errorf("got current Money %v, want 4300", got)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSortedKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"ints", sortedIntKeys(map[string]int{"b": 1, "a": 2, "c": 3}), "[a b c]"},
+		{"floats", sortedFloatKeys(map[string]float64{"Approval": 1, "Aid": 2}), "[Aid Approval]"},
+		{"strings", sortedStringKeys(map[string]string{"y": "", "x": ""}), "[x y]"},
+		{"bools", sortedBoolKeys(nil), "[]"},
+		{"deltas", sortedDeltaKeys(map[string]Delta{"Tax": {}, "Army": {}}), "[Army Tax]"},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprint(test.got); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
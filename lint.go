@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/antonmedv/expr"
+)
+
+// LintWarning is a likely mistake in a rule found by LintGuards.
+type LintWarning struct {
+	// Rule is the name of the rule, or "rule <index>" if it's unnamed.
+	Rule    string
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%v: %v", w.Rule, w.Message)
+}
+
+// worldKeyRef matches references to resources and powers in guards, e.g.
+// World.Resources.Money or World.Powers["Military"].
+var worldKeyRef = regexp.MustCompile(`World\.(?:Resources|Powers|Reals|LastChange)(?:\.([A-Za-z_][A-Za-z0-9_]*)|\["([^"]*)"\])`)
+
+// LintGuards looks for guards that are probably mistakes: those that don't
+// depend on the world, so they always or never pass, and those referring to
+// resources or powers missing from the initial world. Such a key is reported
+// as unknown unless it's in s.Meta or changed by some choice. Only
+// references through World are checked, not flat names. Guards that don't
+// compile are left to Validate.
+func LintGuards(s Scenario) []LintWarning {
+	known := s.knownKeys()
+	initial := s.initialWorld()
+	var warnings []LintWarning
+	for i, rule := range s.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		warn := func(format string, args ...interface{}) {
+			warnings = append(warnings, LintWarning{Rule: name, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if pass, ok := constantGuard(rule.Source); ok {
+			if pass {
+				warn("guard %q always passes", rule.Source)
+			} else {
+				warn("guard %q never passes", rule.Source)
+			}
+		}
+		for _, key := range referencedKeys(rule.Source) {
+			if initial.has(key) {
+				continue
+			}
+			if known[key] {
+				warn("guard %q refers to %v, missing from the initial world", rule.Source, key)
+			} else {
+				warn("guard %q refers to unknown key %v", rule.Source, key)
+			}
+		}
+	}
+	return warnings
+}
+
+// constantGuard evaluates source if it refers to no variable or function,
+// reporting false if it does or doesn't compile.
+func constantGuard(source string) (pass bool, ok bool) {
+	// An empty set of variables turns type checking on, so any name is an
+	// error.
+	node, err := expr.Parse(source, expr.With(map[string]interface{}{}))
+	if err != nil {
+		return false, false
+	}
+	result, err := expr.Run(node, nil)
+	if err != nil {
+		return false, false
+	}
+	pass, ok = result.(bool)
+	return pass, ok
+}
+
+// referencedKeys returns the sorted resources and powers source refers to
+// through World.
+func referencedKeys(source string) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, match := range worldKeyRef.FindAllStringSubmatch(source, -1) {
+		key := match[1]
+		if key == "" {
+			key = match[2]
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (w World) has(key string) bool {
+	_, resource := w.Resources[key]
+	_, power := w.Powers[key]
+	_, fractional := w.Reals[key]
+	return resource || power || fractional
+}
+
+// knownKeys returns the resources and powers in s.Meta or changed by the
+// choices of s.
+func (s Scenario) knownKeys() map[string]bool {
+	known := s.changedKeys()
+	for key := range s.Meta {
+		known[key] = true
+	}
+	return known
+}
+
+// changedKeys returns the resources and powers changed by the choices of s.
+func (s Scenario) changedKeys() map[string]bool {
+	known := make(map[string]bool)
+	addChange := func(change Change) {
+		for key := range change.Resources {
+			known[key] = true
+		}
+		for key := range change.Powers {
+			known[key] = true
+		}
+	}
+	// Follow-up decisions may form a cycle.
+	visited := make(map[*Decision]bool)
+	var addDecision func(decision Decision)
+	addDecision = func(decision Decision) {
+		for _, choice := range decision.Choices {
+			addChange(choice.Change)
+			for _, branch := range choice.Branches {
+				addChange(branch.Change)
+			}
+			if choice.Next != nil && !visited[choice.Next] {
+				visited[choice.Next] = true
+				addDecision(*choice.Next)
+			}
+		}
+	}
+	for _, rule := range s.Rules {
+		addDecision(rule.Decision)
+	}
+	for _, event := range s.Scheduled {
+		addDecision(event.Decision)
+	}
+	for _, decision := range s.AlwaysAvailable {
+		addDecision(decision)
+	}
+	return known
+}
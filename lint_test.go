@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLintGuards(t *testing.T) {
+	tax := Decision{Description: "Tax", Choices: []Choice{
+		{Description: "Raise", Change: Change{Resources: map[string]Delta{"Money": {100, 0, float64(OpAdd)}, "Food": {1, 0}}}},
+	}}
+	tests := []struct {
+		name  string
+		rule  string
+		guard string
+		want  []string
+	}{
+		{"world guard", "a", "World.Resources.Money > 0", []string{}},
+		{"indexed key", "i", `World.Resources["Gold"] > 0`, []string{}},
+		{"always passes", "quit", "true", []string{`quit: guard "true" always passes`}},
+		{"never passes", "b", "1 > 2", []string{`b: guard "1 > 2" never passes`}},
+		{"unnamed", "", "false", []string{`rule 0: guard "false" never passes`}},
+		{"unknown key", "c", "World.Powers.Stability > 10", []string{`c: guard "World.Powers.Stability > 10" refers to unknown key Stability`}},
+		{"initial world", "f", "World.Powers.Military > 10", []string{}},
+		{"missing from initial world", "g", "World.Powers.Legislation > 10", []string{`g: guard "World.Powers.Legislation > 10" refers to Legislation, missing from the initial world`}},
+		{"changed but missing", "h", "World.Resources.Money > 0 and World.Resources.Food > 0", []string{`h: guard "World.Resources.Money > 0 and World.Resources.Food > 0" refers to Food, missing from the initial world`}},
+		{"unknown indexed key", "d", `World.Resources["Oil"] > 0`, []string{`d: guard "World.Resources[\"Oil\"] > 0" refers to unknown key Oil`}},
+	}
+	for _, test := range tests {
+		scenario := Scenario{
+			Rules: []Rule{mustRule(t, test.rule, test.guard, 1, tax)},
+			Meta:  ResourceMeta{"Legislation": Percent},
+			InitialWorld: &World{
+				Resources: map[string]int{"Money": 100, "Gold": 5},
+				Powers:    map[string]int{"Military": 50},
+			},
+		}
+		got := make([]string, 0)
+		for _, warning := range LintGuards(scenario) {
+			got = append(got, warning.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
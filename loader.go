@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// scenarioFile is the on-disk representation of a Scenario.
+type scenarioFile struct {
+	Rules []ruleFile `json:"rules" yaml:"rules" toml:"rules"`
+	// Meta maps resources and powers to a FormatKind name.
+	Meta      map[string]string `json:"meta,omitempty" yaml:"meta" toml:"meta"`
+	Scheduled []scheduledFile   `json:"scheduled,omitempty" yaml:"scheduled" toml:"scheduled"`
+	// InitialWorld, if set, replaces the default initial world.
+	InitialWorld *worldFile `json:"initialWorld,omitempty" yaml:"initialWorld" toml:"initialWorld"`
+	// Decay maps resources and powers to the delta applied to them at the
+	// end of every turn.
+	Decay map[string]deltaFile `json:"decay,omitempty" yaml:"decay" toml:"decay"`
+	// Renamed maps old resource and power names to new ones.
+	Renamed map[string]string `json:"renamed,omitempty" yaml:"renamed" toml:"renamed"`
+	// AlwaysAvailable are decisions offered every turn.
+	AlwaysAvailable []decisionFile `json:"alwaysAvailable,omitempty" yaml:"alwaysAvailable" toml:"alwaysAvailable"`
+	// DynamicKeys allows changing undeclared resources and powers.
+	DynamicKeys bool `json:"dynamicKeys,omitempty" yaml:"dynamicKeys" toml:"dynamicKeys"`
+}
+
+type worldFile struct {
+	Resources map[string]int `json:"resources,omitempty" yaml:"resources" toml:"resources"`
+	Powers    map[string]int `json:"powers,omitempty" yaml:"powers" toml:"powers"`
+}
+
+type scheduledFile struct {
+	Turn     int          `json:"turn,omitempty" yaml:"turn" toml:"turn"`
+	Every    int          `json:"every,omitempty" yaml:"every" toml:"every"`
+	Decision decisionFile `json:"decision" yaml:"decision" toml:"decision"`
+}
+
+type ruleFile struct {
+	Name      string       `json:"name" yaml:"name" toml:"name"`
+	Guard     string       `json:"guard" yaml:"guard" toml:"guard"`
+	Weight    float64      `json:"weight" yaml:"weight" toml:"weight"`
+	Decision  decisionFile `json:"decision" yaml:"decision" toml:"decision"`
+	Cooldown  int          `json:"cooldown,omitempty" yaml:"cooldown" toml:"cooldown"`
+	Once      bool         `json:"once,omitempty" yaml:"once" toml:"once"`
+	Mandatory bool         `json:"mandatory,omitempty" yaml:"mandatory" toml:"mandatory"`
+	Priority  int          `json:"priority,omitempty" yaml:"priority" toml:"priority"`
+	Group     string       `json:"group,omitempty" yaml:"group" toml:"group"`
+	// WeightExpr, if set, is used instead of Weight.
+	WeightExpr string `json:"weightExpr,omitempty" yaml:"weightExpr" toml:"weightExpr"`
+}
+
+type decisionFile struct {
+	Description string       `json:"description" yaml:"description" toml:"description"`
+	Key         string       `json:"key,omitempty" yaml:"key" toml:"key"`
+	Choices     []choiceFile `json:"choices" yaml:"choices" toml:"choices"`
+	Tags        []string     `json:"tags,omitempty" yaml:"tags" toml:"tags"`
+	// ExpiresInTurns and Default make the decision expire unless chosen.
+	ExpiresInTurns int         `json:"expiresInTurns,omitempty" yaml:"expiresInTurns" toml:"expiresInTurns"`
+	Default        *choiceFile `json:"default,omitempty" yaml:"default" toml:"default"`
+}
+
+// A choice without a change (e.g. "Quit") results in an empty Change.
+type choiceFile struct {
+	Description string        `json:"description" yaml:"description" toml:"description"`
+	Key         string        `json:"key,omitempty" yaml:"key" toml:"key"`
+	Guard       string        `json:"guard,omitempty" yaml:"guard" toml:"guard"`
+	Change      changeFile    `json:"change" yaml:"change" toml:"change"`
+	Branches    []branchFile  `json:"branches,omitempty" yaml:"branches" toml:"branches"`
+	Next        *decisionFile `json:"next,omitempty" yaml:"next" toml:"next"`
+}
+
+type branchFile struct {
+	Guard  string     `json:"guard" yaml:"guard" toml:"guard"`
+	Change changeFile `json:"change" yaml:"change" toml:"change"`
+}
+
+type changeFile struct {
+	Resources map[string]deltaFile `json:"resources,omitempty" yaml:"resources" toml:"resources"`
+	Powers    map[string]deltaFile `json:"powers,omitempty" yaml:"powers" toml:"powers"`
+}
+
+// deltaFile is a Delta, written either as an array of numbers or as a named
+// op, e.g. "percent(10)" for PercentChange(10) or "scaleTo(0.1)" for
+// ScaleTo(0.1).
+type deltaFile []float64
+
+var namedDeltas = map[string]func(float64) Delta{
+	"percent": PercentChange,
+	"scaleTo": ScaleTo,
+}
+
+var namedDelta = regexp.MustCompile(`^\s*([A-Za-z]+)\(\s*([^()\s]+)\s*\)\s*$`)
+
+func (d *deltaFile) parse(s string) error {
+	match := namedDelta.FindStringSubmatch(s)
+	if match == nil {
+		return fmt.Errorf("invalid delta %q, expected e.g. \"percent(10)\"", s)
+	}
+	op, ok := namedDeltas[match[1]]
+	if !ok {
+		return fmt.Errorf("unknown delta op %q", match[1])
+	}
+	v, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid delta %q: %v", s, err)
+	}
+	*d = deltaFile(op(v))
+	return nil
+}
+
+func (d *deltaFile) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return d.parse(s)
+	}
+	var values []float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*d = values
+	return nil
+}
+
+func (d *deltaFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		return d.parse(s)
+	}
+	var values []float64
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+	*d = values
+	return nil
+}
+
+func (d *deltaFile) UnmarshalTOML(data interface{}) error {
+	switch data := data.(type) {
+	case string:
+		return d.parse(data)
+	case []interface{}:
+		values := make([]float64, len(data))
+		for i, v := range data {
+			switch v := v.(type) {
+			case float64:
+				values[i] = v
+			case int64:
+				values[i] = float64(v)
+			default:
+				return fmt.Errorf("invalid delta element %v", v)
+			}
+		}
+		*d = values
+		return nil
+	default:
+		return fmt.Errorf("invalid delta %v", data)
+	}
+}
+
+// LoadScenario reads a scenario from a JSON file.
+func LoadScenario(path string) (Scenario, error) {
+	return loadScenarioFile(path, json.Unmarshal)
+}
+
+// LoadScenarioYAML reads a scenario from a YAML file. The document has the
+// same structure as the one accepted by LoadScenario.
+func LoadScenarioYAML(path string) (Scenario, error) {
+	return loadScenarioFile(path, yaml.Unmarshal)
+}
+
+// LoadScenarioTOML reads a scenario from a TOML file with the same
+// structure as the one accepted by LoadScenario: a [[rules]] array of tables,
+// each with a [rules.decision] table holding [[rules.decision.choices]].
+func LoadScenarioTOML(path string) (Scenario, error) {
+	return loadScenarioFile(path, toml.Unmarshal)
+}
+
+func loadScenarioFile(path string, unmarshal func([]byte, interface{}) error) (Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var file scenarioFile
+	if err := unmarshal(data, &file); err != nil {
+		return Scenario{}, fmt.Errorf("%v: %v", path, err)
+	}
+	return file.Scenario()
+}
+
+func (f scenarioFile) Scenario() (Scenario, error) {
+	rules := make([]Rule, len(f.Rules))
+	for i, r := range f.Rules {
+		decision, err := r.Decision.Decision()
+		if err != nil {
+			return Scenario{}, fmt.Errorf("rule %d: %v", i, err)
+		}
+		rule, err := NewRule(r.Name, r.Guard, r.Weight, decision)
+		if err != nil {
+			return Scenario{}, fmt.Errorf("rule %d: invalid guard %q: %v", i, r.Guard, err)
+		}
+		rule.Cooldown = r.Cooldown
+		rule.Once = r.Once
+		rule.Mandatory = r.Mandatory
+		rule.Priority = r.Priority
+		rule.Group = r.Group
+		if r.WeightExpr != "" {
+			rule.WeightExpr, err = NewNumberExpr(r.WeightExpr)
+			if err != nil {
+				return Scenario{}, fmt.Errorf("rule %d: invalid weight expression %q: %v", i, r.WeightExpr, err)
+			}
+		}
+		rules[i] = rule
+	}
+	meta, err := resourceMeta(f.Meta)
+	if err != nil {
+		return Scenario{}, err
+	}
+	scheduled := make([]ScheduledEvent, len(f.Scheduled))
+	for i, e := range f.Scheduled {
+		decision, err := e.Decision.Decision()
+		if err != nil {
+			return Scenario{}, fmt.Errorf("scheduled event %d: %v", i, err)
+		}
+		scheduled[i] = ScheduledEvent{Turn: e.Turn, Every: e.Every, Decision: decision}
+	}
+	decay, err := deltas(f.Decay)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("decay: %v", err)
+	}
+	var always []Decision
+	for i, d := range f.AlwaysAvailable {
+		decision, err := d.Decision()
+		if err != nil {
+			return Scenario{}, fmt.Errorf("always available decision %d: %v", i, err)
+		}
+		always = append(always, decision)
+	}
+	scenario := Scenario{Rules: rules, Meta: meta, Scheduled: scheduled, Decay: decay, Renamed: f.Renamed, AlwaysAvailable: always, DynamicKeys: f.DynamicKeys}
+	if f.InitialWorld != nil {
+		scenario.InitialWorld = &World{
+			Resources: copyValues(f.InitialWorld.Resources),
+			Powers:    copyValues(f.InitialWorld.Powers),
+		}
+	}
+	if err := scenario.Validate(); err != nil {
+		return Scenario{}, err
+	}
+	return scenario, nil
+}
+
+func resourceMeta(m map[string]string) (ResourceMeta, error) {
+	if m == nil {
+		return nil, nil
+	}
+	meta := make(ResourceMeta, len(m))
+	for key, name := range m {
+		var kind FormatKind
+		if err := kind.UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("meta %v: %v", key, err)
+		}
+		meta[key] = kind
+	}
+	return meta, nil
+}
+
+func formatNames(meta ResourceMeta) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]string, len(meta))
+	for key, kind := range meta {
+		out[key] = kind.String()
+	}
+	return out
+}
+
+func (f decisionFile) Decision() (Decision, error) {
+	choices := make([]Choice, len(f.Choices))
+	for i, c := range f.Choices {
+		choice, err := c.Choice()
+		if err != nil {
+			return Decision{}, fmt.Errorf("choice %q: %v", c.Description, err)
+		}
+		choices[i] = choice
+	}
+	decision := Decision{
+		Description:    f.Description,
+		Key:            f.Key,
+		Choices:        choices,
+		Tags:           f.Tags,
+		ExpiresInTurns: f.ExpiresInTurns,
+	}
+	if f.Default != nil {
+		choice, err := f.Default.Choice()
+		if err != nil {
+			return Decision{}, fmt.Errorf("default: %v", err)
+		}
+		decision.Default = &choice
+	}
+	return decision, nil
+}
+
+func (f choiceFile) Choice() (Choice, error) {
+	change, err := f.Change.Change()
+	if err != nil {
+		return Choice{}, err
+	}
+	choice := Choice{
+		Description: f.Description,
+		Key:         f.Key,
+		Change:      change,
+	}
+	if f.Guard != "" {
+		guard, err := NewGuard(f.Guard)
+		if err != nil {
+			return Choice{}, fmt.Errorf("invalid guard %q: %v", f.Guard, err)
+		}
+		choice.Guard = &guard
+	}
+	for j, b := range f.Branches {
+		branch, err := b.Branch()
+		if err != nil {
+			return Choice{}, fmt.Errorf("branch %d: %v", j, err)
+		}
+		choice.Branches = append(choice.Branches, branch)
+	}
+	if f.Next != nil {
+		next, err := f.Next.Decision()
+		if err != nil {
+			return Choice{}, fmt.Errorf("next: %v", err)
+		}
+		choice.Next = &next
+	}
+	return choice, nil
+}
+
+func (f branchFile) Branch() (Branch, error) {
+	guard, err := NewGuard(f.Guard)
+	if err != nil {
+		return Branch{}, fmt.Errorf("invalid guard %q: %v", f.Guard, err)
+	}
+	change, err := f.Change.Change()
+	if err != nil {
+		return Branch{}, err
+	}
+	return Branch{Guard: guard, Change: change}, nil
+}
+
+func (f changeFile) Change() (Change, error) {
+	resources, err := deltas(f.Resources)
+	if err != nil {
+		return Change{}, fmt.Errorf("resources: %v", err)
+	}
+	powers, err := deltas(f.Powers)
+	if err != nil {
+		return Change{}, fmt.Errorf("powers: %v", err)
+	}
+	return Change{
+		Resources: resources,
+		Powers:    powers,
+	}, nil
+}
+
+func deltas(m map[string]deltaFile) (map[string]Delta, error) {
+	if m == nil {
+		return nil, nil
+	}
+	out := make(map[string]Delta, len(m))
+	for k, v := range m {
+		delta := Delta(v)
+		if err := delta.check(); err != nil {
+			return nil, fmt.Errorf("%v: %v", k, err)
+		}
+		out[k] = delta
+	}
+	return out, nil
+}
+
+// MarshalJSON encodes the scenario in the format read by LoadScenario.
+func (s Scenario) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newScenarioFile(s))
+}
+
+func newScenarioFile(s Scenario) scenarioFile {
+	rules := make([]ruleFile, len(s.Rules))
+	for i, r := range s.Rules {
+		rules[i] = ruleFile{
+			Name:      r.Name,
+			Guard:     r.Guard.Source,
+			Weight:    r.Weight,
+			Decision:  newDecisionFile(r.Decision),
+			Cooldown:  r.Cooldown,
+			Once:      r.Once,
+			Mandatory: r.Mandatory,
+			Priority:  r.Priority,
+			Group:     r.Group,
+		}
+		if r.WeightExpr != nil {
+			rules[i].WeightExpr = r.WeightExpr.Source
+		}
+	}
+	var scheduled []scheduledFile
+	for _, e := range s.Scheduled {
+		scheduled = append(scheduled, scheduledFile{
+			Turn:     e.Turn,
+			Every:    e.Every,
+			Decision: newDecisionFile(e.Decision),
+		})
+	}
+	var always []decisionFile
+	for _, d := range s.AlwaysAvailable {
+		always = append(always, newDecisionFile(d))
+	}
+	f := scenarioFile{Rules: rules, Meta: formatNames(s.Meta), Scheduled: scheduled, Decay: floats(s.Decay), Renamed: s.Renamed, AlwaysAvailable: always, DynamicKeys: s.DynamicKeys}
+	if s.InitialWorld != nil {
+		f.InitialWorld = &worldFile{
+			Resources: s.InitialWorld.Resources,
+			Powers:    s.InitialWorld.Powers,
+		}
+	}
+	return f
+}
+
+func newDecisionFile(d Decision) decisionFile {
+	choices := make([]choiceFile, len(d.Choices))
+	for i, c := range d.Choices {
+		choices[i] = newChoiceFile(c)
+	}
+	f := decisionFile{
+		Description:    d.Description,
+		Key:            d.Key,
+		Choices:        choices,
+		Tags:           d.Tags,
+		ExpiresInTurns: d.ExpiresInTurns,
+	}
+	if d.Default != nil {
+		choice := newChoiceFile(*d.Default)
+		f.Default = &choice
+	}
+	return f
+}
+
+func newChoiceFile(c Choice) choiceFile {
+	f := choiceFile{
+		Description: c.Description,
+		Key:         c.Key,
+		Change:      newChangeFile(c.Change),
+	}
+	if c.Guard != nil {
+		f.Guard = c.Guard.Source
+	}
+	for _, branch := range c.Branches {
+		f.Branches = append(f.Branches, branchFile{
+			Guard:  branch.Guard.Source,
+			Change: newChangeFile(branch.Change),
+		})
+	}
+	if c.Next != nil {
+		next := newDecisionFile(*c.Next)
+		f.Next = &next
+	}
+	return f
+}
+
+func newChangeFile(c Change) changeFile {
+	return changeFile{
+		Resources: floats(c.Resources),
+		Powers:    floats(c.Powers),
+	}
+}
+
+func floats(m map[string]Delta) map[string]deltaFile {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]deltaFile, len(m))
+	for k, v := range m {
+		out[k] = deltaFile(v)
+	}
+	return out
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("scenarios/simple.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenario.Rules) != 2 {
+		t.Fatalf("got %v rules, want 2", len(scenario.Rules))
+	}
+
+	putsch, quit := scenario.Rules[0], scenario.Rules[1]
+	if putsch.Name != "putsch" || quit.Name != "quit" {
+		t.Errorf("got rules %q and %q, want putsch and quit", putsch.Name, quit.Name)
+	}
+	wantPutsch := Decision{
+		Description: "Make putsch",
+		Choices: []Choice{
+			{
+				Description: "Accept",
+				Change: Change{
+					Resources: map[string]Delta{"Money": {0.5, 0}, "Popularity": {0, 0}},
+					Powers:    map[string]Delta{"Legislation": {0, 100}},
+				},
+			},
+			{
+				Description: "Reject",
+				Change: Change{
+					Powers: map[string]Delta{"Military": {0.1, 0}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(putsch.Decision, wantPutsch) {
+		t.Errorf("got decision %+v, want %+v", putsch.Decision, wantPutsch)
+	}
+	wantQuit := Decision{
+		Description: "Quit",
+		Choices:     []Choice{{Description: "Accept"}},
+	}
+	if !reflect.DeepEqual(quit.Decision, wantQuit) {
+		t.Errorf("got decision %+v, want %+v", quit.Decision, wantQuit)
+	}
+	for i, rule := range scenario.Rules {
+		if rule.Weight != 1 {
+			t.Errorf("rule %d: got weight %v, want 1", i, rule.Weight)
+		}
+	}
+
+	tests := []struct {
+		world  World
+		putsch bool
+		quit   bool
+	}{
+		{World{Resources: map[string]int{"Money": 1001}, Powers: map[string]int{"Military": 90}}, true, true},
+		{World{Resources: map[string]int{"Money": 1000}, Powers: map[string]int{"Military": 90}}, false, true},
+		{World{Resources: map[string]int{"Money": 1001}, Powers: map[string]int{"Military": 89}}, false, true},
+	}
+	for _, test := range tests {
+		if pass, err := putsch.Pass(test.world); err != nil || pass != test.putsch {
+			t.Errorf("putsch guard in %v: got %v, %v, want %v", test.world, pass, err, test.putsch)
+		}
+		if pass, err := quit.Pass(test.world); err != nil || pass != test.quit {
+			t.Errorf("quit guard in %v: got %v, %v, want %v", test.world, pass, err, test.quit)
+		}
+	}
+}
+
+func TestScenarioFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "invalid guard",
+			data: `{"rules": [{"guard": "true", "weight": 1}, {"guard": "World.Resources.Money >", "weight": 1}]}`,
+			err:  `rule 1: invalid guard "World.Resources.Money >"`,
+		},
+		{
+			name: "short delta",
+			data: `{"rules": [{"guard": "true", "decision": {"choices": [{"description": "A", "change": {"resources": {"Money": [1]}}}]}}]}`,
+			err:  `rule 0: choice "A": resources: Money: delta must have 2 or 3 elements, got 1`,
+		},
+		{
+			name: "long delta",
+			data: `{"rules": [{"guard": "true", "decision": {"choices": [{"description": "A", "change": {"powers": {"Military": [1, 2, 3, 4]}}}]}}]}`,
+			err:  `rule 0: choice "A": powers: Military: delta must have 2 or 3 elements, got 4`,
+		},
+		{
+			name: "invalid choice guard",
+			data: `{"rules": [{"guard": "true", "decision": {"choices": [{"description": "A", "guard": "World.Resources.Money >"}]}}]}`,
+			err:  `rule 0: choice "A": invalid guard "World.Resources.Money >"`,
+		},
+		{
+			name: "invalid branch guard",
+			data: `{"rules": [{"guard": "true", "decision": {"choices": [{"description": "A", "branches": [{"guard": "1 +"}]}]}}]}`,
+			err:  `rule 0: choice "A": branch 0: invalid guard "1 +"`,
+		},
+		{
+			name: "unknown format",
+			data: `{"rules": [], "meta": {"Money": "euro"}}`,
+			err:  `meta Money: unknown format "euro"`,
+		},
+		{
+			name: "invalid weight expression",
+			data: `{"rules": [{"guard": "true", "weightExpr": "1 -", "decision": {"choices": [{"description": "A"}]}}]}`,
+			err:  `rule 0: invalid weight expression "1 -"`,
+		},
+		{
+			name: "unknown op",
+			data: `{"rules": [{"guard": "true", "decision": {"choices": [{"description": "A", "change": {"powers": {"Military": [1, 2, 9]}}}]}}]}`,
+			err:  `rule 0: choice "A": powers: Military: unknown delta op 9`,
+		},
+	}
+	for _, test := range tests {
+		var file scenarioFile
+		if err := json.Unmarshal([]byte(test.data), &file); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		_, err := file.Scenario()
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestLoadScenarioYAML(t *testing.T) {
+	scenario, err := LoadScenarioYAML("scenarios/simple.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenario.Rules) != 2 {
+		t.Fatalf("got %v rules, want 2", len(scenario.Rules))
+	}
+	accept := scenario.Rules[0].Choices[0]
+	world := World{
+		Resources: map[string]int{"Money": 2000},
+		Powers:    map[string]int{"Legislation": 50},
+	}
+	if err := world.Apply(accept, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Money is halved and reduced by 200, Legislation scaled by 0.1.
+	if world.Resources["Money"] != 800 || world.Powers["Legislation"] != 5 {
+		t.Errorf("got %v, want Money 800 and Legislation 5", world)
+	}
+	if quit := scenario.Rules[1].Choices[0]; !reflect.DeepEqual(quit.Change, Change{}) {
+		t.Errorf("got change %+v for a choice without change, want an empty one", quit.Change)
+	}
+}
+
+func TestJSONAndYAMLScenariosEqual(t *testing.T) {
+	const jsonData = `{"rules": [{"guard": "World.Resources.Money > 1000", "weight": 0.5, "decision": {
+		"description": "Tax", "choices": [
+			{"description": "Raise", "change": {"resources": {"Money": [1.1, 10]}, "powers": {"Legislation": [1, -5]}}},
+			{"description": "Ignore"}]}}]}`
+	const yamlData = `
+rules:
+  - guard: World.Resources.Money > 1000
+    weight: 0.5
+    decision:
+      description: Tax
+      choices:
+        - description: Raise
+          change:
+            resources:
+              Money: [1.1, 10]
+            powers:
+              Legislation: [1, -5]
+        - description: Ignore
+`
+	var fromJSON, fromYAML scenarioFile
+	if err := json.Unmarshal([]byte(jsonData), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(yamlData), &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	jsonScenario, err := fromJSON.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlScenario, err := fromYAML.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jsonScenario, yamlScenario) {
+		t.Errorf("got %+v from YAML, want %+v as from JSON", yamlScenario, jsonScenario)
+	}
+}
+
+func TestLoadScenarioTOML(t *testing.T) {
+	fromJSON, err := LoadScenario("scenarios/simple.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromTOML, err := LoadScenarioTOML("scenarios/simple.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromTOML, fromJSON) {
+		t.Errorf("got %+v from TOML, want %+v as from JSON", fromTOML, fromJSON)
+	}
+}
+
+func TestScenarioMarshalJSON(t *testing.T) {
+	tests := []struct {
+		path string
+		load func(string) (Scenario, error)
+	}{
+		{"scenarios/simple.json", LoadScenario},
+		{"scenarios/simple.yaml", LoadScenarioYAML},
+		{"scenarios/simple.toml", LoadScenarioTOML},
+	}
+	for _, test := range tests {
+		scenario, err := test.load(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(scenario)
+		if err != nil {
+			t.Fatalf("%v: %v", test.path, err)
+		}
+		var file scenarioFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("%v: %v", test.path, err)
+		}
+		reloaded, err := file.Scenario()
+		if err != nil {
+			t.Fatalf("%v: %v", test.path, err)
+		}
+		if !reflect.DeepEqual(reloaded, scenario) {
+			t.Errorf("%v: got %+v after a round trip, want %+v", test.path, reloaded, scenario)
+		}
+		if got := file.Rules[0].Guard; got != scenario.Rules[0].Source {
+			t.Errorf("%v: got guard %q, want %q", test.path, got, scenario.Rules[0].Source)
+		}
+	}
+}
+
+func TestScenarioFileRuleOptions(t *testing.T) {
+	const data = `{"rules": [
+		{"name": "a", "guard": "true", "weight": 1, "cooldown": 3, "priority": 2, "decision": {"choices": [{"description": "Accept"}]}},
+		{"name": "b", "guard": "true", "weight": 1, "once": true, "mandatory": true, "decision": {"choices": [{"description": "Accept"}]}}]}`
+	var file scenarioFile
+	if err := json.Unmarshal([]byte(data), &file); err != nil {
+		t.Fatal(err)
+	}
+	scenario, err := file.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := scenario.Rules[0]; got.Cooldown != 3 || got.Once || got.Priority != 2 {
+		t.Errorf("got cooldown %v, once %v and priority %v, want 3, false and 2", got.Cooldown, got.Once, got.Priority)
+	}
+	if got := scenario.Rules[1]; got.Cooldown != 0 || !got.Once || !got.Mandatory {
+		t.Errorf("got cooldown %v, once %v and mandatory %v, want 0, true and true", got.Cooldown, got.Once, got.Mandatory)
+	}
+	if got := newScenarioFile(scenario); got.Rules[0].Cooldown != 3 || got.Rules[0].Priority != 2 || !got.Rules[1].Once || !got.Rules[1].Mandatory {
+		t.Errorf("got %+v after a round trip", got)
+	}
+}
+
+func TestScenarioFileDuplicateNames(t *testing.T) {
+	const data = `{"rules": [{"name": "a", "guard": "true"}, {"name": "a", "guard": "false"}]}`
+	var file scenarioFile
+	if err := json.Unmarshal([]byte(data), &file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Scenario(); err == nil {
+		t.Errorf("got no error for duplicate rule names")
+	}
+}
+
+func TestScenarioFileBranches(t *testing.T) {
+	const data = `
+rules:
+  - name: bribe
+    guard: "true"
+    decision:
+      choices:
+        - description: Bribe
+          guard: World.Resources.Money > 100
+          change:
+            resources:
+              Money: [1, -100]
+          branches:
+            - guard: World.Powers.Legislation < 20
+              change:
+                resources:
+                  Money: [1, -200]
+`
+	var file scenarioFile
+	if err := yaml.Unmarshal([]byte(data), &file); err != nil {
+		t.Fatal(err)
+	}
+	scenario, err := file.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bribe := scenario.Rules[0].Choices[0]
+	if bribe.Guard == nil || bribe.Guard.Source != "World.Resources.Money > 100" {
+		t.Errorf("got guard %+v, want World.Resources.Money > 100", bribe.Guard)
+	}
+	if len(bribe.Branches) != 1 || bribe.Branches[0].Guard.Source != "World.Powers.Legislation < 20" {
+		t.Fatalf("got branches %+v, want one guarded by World.Powers.Legislation < 20", bribe.Branches)
+	}
+	if got := bribe.Branches[0].Change.Resources["Money"]; !reflect.DeepEqual(got, Delta{1, -200}) {
+		t.Errorf("got branch delta %v, want [1 -200]", got)
+	}
+
+	round := newScenarioFile(scenario).Rules[0].Decision.Choices[0]
+	if round.Guard != bribe.Guard.Source || len(round.Branches) != 1 || round.Branches[0].Guard != bribe.Branches[0].Guard.Source {
+		t.Errorf("got %+v after a round trip", round)
+	}
+}
+
+func TestScenarioFileInitialWorld(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want World
+	}{
+		{
+			name: "default",
+			data: `{"rules": []}`,
+			want: World{Resources: map[string]int{"Money": 4000}, Powers: map[string]int{"Military": 90, "Legislation": 10}},
+		},
+		{
+			name: "custom",
+			data: `{"initialWorld": {"resources": {"Money": 100, "Food": 20}, "powers": {"Church": 5}}}`,
+			want: World{Resources: map[string]int{"Money": 100, "Food": 20}, Powers: map[string]int{"Church": 5}},
+		},
+		{
+			name: "no powers",
+			data: `{"initialWorld": {"resources": {"Money": 100}}}`,
+			want: World{Resources: map[string]int{"Money": 100}, Powers: map[string]int{}},
+		},
+	}
+	for _, test := range tests {
+		var file scenarioFile
+		if err := json.Unmarshal([]byte(test.data), &file); err != nil {
+			t.Fatal(err)
+		}
+		scenario, err := file.Scenario()
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		e, err := NewEngine(scenario, GameConfig{Seed: 1})
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		world := e.Current()
+		if !reflect.DeepEqual(world.Resources, test.want.Resources) || !reflect.DeepEqual(world.Powers, test.want.Powers) {
+			t.Errorf("%v: got initial world %+v, want %+v", test.name, world, test.want)
+		}
+		if round := newScenarioFile(scenario).InitialWorld; (round != nil) != (file.InitialWorld != nil) {
+			t.Errorf("%v: got initial world %+v after a round trip, want %+v", test.name, round, file.InitialWorld)
+		}
+	}
+}
+
+func TestNamedDeltas(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   Delta
+		err    string
+	}{
+		{name: "JSON array", format: "json", data: `[1.5, 2]`, want: Delta{1.5, 2}},
+		{name: "JSON percent", format: "json", data: `"percent(10)"`, want: PercentChange(10)},
+		{name: "JSON scaleTo", format: "json", data: `" scaleTo( 0.1 ) "`, want: ScaleTo(0.1)},
+		{name: "YAML array", format: "yaml", data: `[1, -100]`, want: Delta{1, -100}},
+		{name: "YAML percent", format: "yaml", data: `percent(-25)`, want: PercentChange(-25)},
+		{name: "TOML array", format: "toml", data: `[1, 2]`, want: Delta{1, 2}},
+		{name: "TOML scaleTo", format: "toml", data: `"scaleTo(2)"`, want: ScaleTo(2)},
+		{name: "unknown op", format: "json", data: `"double(2)"`, err: `unknown delta op "double"`},
+		{name: "invalid form", format: "yaml", data: `percent 10`, err: `invalid delta "percent 10"`},
+		{name: "invalid number", format: "toml", data: `"percent(ten)"`, err: `invalid delta "percent(ten)"`},
+	}
+	for _, test := range tests {
+		var change struct {
+			Money deltaFile `json:"money" yaml:"money" toml:"money"`
+		}
+		var err error
+		switch test.format {
+		case "json":
+			err = json.Unmarshal([]byte(`{"money": `+test.data+`}`), &change)
+		case "yaml":
+			err = yaml.Unmarshal([]byte("money: "+test.data), &change)
+		case "toml":
+			_, err = toml.Decode("money = "+test.data, &change)
+		}
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if got := Delta(change.Money); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestScenarioFileAlwaysAvailable(t *testing.T) {
+	data := `{"alwaysAvailable": [{"description": "End Game", "choices": [{"description": "Resign", "change": {"resources": {"Money": [0, 0]}}}]}]}`
+	var file scenarioFile
+	if err := json.Unmarshal([]byte(data), &file); err != nil {
+		t.Fatal(err)
+	}
+	scenario, err := file.Scenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenario.AlwaysAvailable) != 1 || scenario.AlwaysAvailable[0].Choices[0].Description != "Resign" {
+		t.Fatalf("got always available decisions %+v", scenario.AlwaysAvailable)
+	}
+	if round := newScenarioFile(scenario).AlwaysAvailable; !reflect.DeepEqual(round, file.AlwaysAvailable) {
+		t.Errorf("got %+v after a round trip, want %+v", round, file.AlwaysAvailable)
+	}
+
+	scenario.AlwaysAvailable = append(scenario.AlwaysAvailable, Decision{Description: "Empty"})
+	if err := scenario.Validate(); err == nil || err.Error() != `always available decision 1: decision "Empty" has no choices` {
+		t.Errorf("got error %v, want one about the decision without choices", err)
+	}
+}
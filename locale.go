@@ -0,0 +1,31 @@
+package main
+
+// Translator maps a locale, e.g. "en", to the text of each message key.
+type Translator map[string]map[string]string
+
+// Text returns the text of key in locale, or key itself if it has no
+// translation.
+func (t Translator) Text(locale, key string) string {
+	if text, ok := t[locale][key]; ok {
+		return text
+	}
+	return key
+}
+
+// Localized returns a copy of the decision whose description and choice
+// descriptions are resolved from their message keys in locale. Descriptions
+// without a key are left as they are.
+func (d Decision) Localized(t Translator, locale string) Decision {
+	if d.Key != "" {
+		d.Description = t.Text(locale, d.Key)
+	}
+	choices := make([]Choice, len(d.Choices))
+	for i, choice := range d.Choices {
+		if choice.Key != "" {
+			choice.Description = t.Text(locale, choice.Key)
+		}
+		choices[i] = choice
+	}
+	d.Choices = choices
+	return d
+}
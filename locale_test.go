@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDecisionLocalized(t *testing.T) {
+	translator := Translator{
+		"en": {"putsch": "Make putsch", "accept": "Accept", "reject": "Reject"},
+		"pl": {"putsch": "Zrób pucz", "accept": "Zgódź się"},
+	}
+	decision := Decision{
+		Description: "Putsch",
+		Key:         "putsch",
+		Choices: []Choice{
+			{Description: "Yes", Key: "accept"},
+			{Description: "No", Key: "reject"},
+			{Description: "Wait"},
+		},
+	}
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "Make putsch: [Accept Reject Wait]"},
+		{"pl", "Zrób pucz: [Zgódź się reject Wait]"},
+		{"de", "putsch: [accept reject Wait]"},
+	}
+	for _, test := range tests {
+		localized := decision.Localized(translator, test.locale)
+		choices := make([]string, len(localized.Choices))
+		for i, choice := range localized.Choices {
+			choices[i] = choice.Description
+		}
+		if got := fmt.Sprintf("%v: %v", localized.Description, choices); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.locale, got, test.want)
+		}
+	}
+	if decision.Description != "Putsch" || decision.Choices[0].Description != "Yes" {
+		t.Errorf("got %+v, want the decision unchanged", decision)
+	}
+	if got := decision.Localized(nil, "en").Description; got != "putsch" {
+		t.Errorf("got %q without a translator, want the key", got)
+	}
+}
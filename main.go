@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/antonmedv/expr"
 	"github.com/davecgh/go-spew/spew"
@@ -18,82 +23,623 @@ import (
 type World struct {
 	Resources map[string]int
 	Powers    map[string]int
+	// Reals holds fractional resources, e.g. an approval rating of 62.5,
+	// that are never rounded. Change.Resources changes a resource in Reals
+	// rather than in Resources if it's there.
+	Reals map[string]float64
+	// Turn is the number of turns played so far.
+	Turn int
+	// Bounds optionally limits resources and powers to [min, max].
+	// Keys without bounds are unlimited.
+	Bounds map[string][2]int
+	// Derived maps resources computed from the rest of the world to their
+	// expressions, e.g. "World.Resources.Money / 100 + World.Powers.Legislation".
+	// They are recomputed after each Apply, compiled the first time, so
+	// they mustn't change once the world is in use.
+	Derived map[string]string
+	// NonNegative marks resources and powers that can't drop below zero,
+	// e.g. Military, unlike Money that can go into debt. It's applied on
+	// top of Bounds.
+	NonNegative map[string]bool
+	// StrictBounds makes Apply fail instead of clamping values pushed out
+	// of Bounds or below zero despite NonNegative.
+	StrictBounds bool
+	// LastChange maps each resource and power to how much it changed in
+	// the last Apply, e.g. to gate rules on World.LastChange.Money < -1000.
+	LastChange map[string]int
+	// Rounding rounds changed values unless RoundingByKey overrides it for
+	// their resource or power.
+	Rounding      RoundingMode
+	RoundingByKey map[string]RoundingMode
+	// past holds the worlds of previous turns, if known, for the history
+	// guard function.
+	past *pastWorlds
+	// state tracks the choices made, if known, for the fired and
+	// choseChoice guard functions.
+	state *RuleState
+	// derived holds the compiled Derived expressions, if compiled yet.
+	derived *compiledDerived
 }
 
+// WithBounds returns w with each bounded key limited to [min, max].
+func (w World) WithBounds(bounds map[string][2]int) World {
+	w.Bounds = bounds
+	return w
+}
+
+// Copy returns a deep copy of the world that can be modified independently.
 func (w World) Copy() World {
-	copy := World{}
+	copy := World{past: w.past, state: w.state, derived: w.derived}
 	copier.Copy(&copy, &w)
+	copy.Resources = copyValues(w.Resources)
+	copy.Powers = copyValues(w.Powers)
+	copy.LastChange = copyValues(w.LastChange)
+	if w.Reals != nil {
+		copy.Reals = make(map[string]float64, len(w.Reals))
+		for k, v := range w.Reals {
+			copy.Reals[k] = v
+		}
+	}
 	return copy
 }
 
+func copyValues(m map[string]int) map[string]int {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// Delta describes how a value changes. The two-element form {a, b} sets
+// the value to a*old + b. An optional third element selects a different
+// DeltaOp.
+//
+// Note that {0.1, 0} scales the value to 10% of itself rather than
+// increasing it by 10%, which is {1.1, 0}; PercentChange and ScaleTo spell
+// out the difference.
 type Delta []float64
 
+// PercentChange returns a delta changing the value by percent, e.g. 10
+// turns 100 into 110 and -10 turns it into 90.
+func PercentChange(percent float64) Delta {
+	return Delta{1 + percent/100, 0}
+}
+
+// ScaleTo returns a delta scaling the value to factor times itself, e.g.
+// 0.1 turns 100 into 10.
+func ScaleTo(factor float64) Delta {
+	return Delta{factor, 0}
+}
+
+// DeltaOp is an operation applied by a Delta.
+type DeltaOp int
+
+const (
+	// OpLinear sets the value to d[0]*old + d[1].
+	OpLinear DeltaOp = iota
+	// OpSet sets the value to d[0].
+	OpSet
+	// OpAdd adds d[0] to the value.
+	OpAdd
+	// OpMul multiplies the value by d[0].
+	OpMul
+	// OpAddPercent increases the value by d[0] percent, then adds d[1].
+	OpAddPercent
+	// OpAddRandom adds a random value sampled uniformly from [d[0], d[1]].
+	OpAddRandom
+)
+
+// Op returns the operation applied by the delta.
+func (d Delta) Op() DeltaOp {
+	if len(d) < 3 {
+		return OpLinear
+	}
+	return DeltaOp(d[2])
+}
+
+func (d Delta) check() error {
+	if len(d) != 2 && len(d) != 3 {
+		return fmt.Errorf("delta must have 2 or 3 elements, got %v", len(d))
+	}
+	if len(d) == 3 {
+		op := d.Op()
+		if float64(op) != d[2] || op < OpLinear || op > OpAddRandom {
+			return fmt.Errorf("unknown delta op %v", d[2])
+		}
+	}
+	return nil
+}
+
+func (d Delta) apply(old float64, r Rand) float64 {
+	switch d.Op() {
+	case OpSet:
+		return d[0]
+	case OpAdd:
+		return old + d[0]
+	case OpMul:
+		return old * d[0]
+	case OpAddPercent:
+		return old + old*d[0]/100 + d[1]
+	case OpAddRandom:
+		return old + d[0] + r.Float64()*(d[1]-d[0])
+	default:
+		return d[0]*old + d[1]
+	}
+}
+
 type Change struct {
 	Resources map[string]Delta
 	Powers    map[string]Delta
 }
 
+func (c Change) random() bool {
+	for _, delta := range c.Resources {
+		if delta.Op() == OpAddRandom {
+			return true
+		}
+	}
+	for _, delta := range c.Powers {
+		if delta.Op() == OpAddRandom {
+			return true
+		}
+	}
+	return false
+}
+
 type Decision struct {
 	Description string
-	Choices     []Choice
+	// Key, if set, is the message key the description is translated from.
+	Key     string
+	Choices []Choice
+	// Tags categorize the decision, e.g. "Economy" or "Military".
+	Tags []string
+	// Rule is the name of the rule that offered the decision, if any.
+	Rule string
+	// Expires, if set, is how long the decision stays offered, as told by
+	// GameConfig.Clock. Expired decisions can't be chosen and are withdrawn
+	// by Engine.Tick.
+	Expires time.Duration
+	// ExpiresInTurns, if set, keeps the decision offered from the turn it's
+	// first offered on until it's chosen or that many turns have passed,
+	// after which it's withdrawn.
+	ExpiresInTurns int
+	// Default, if set, is applied when the decision expires without being
+	// chosen.
+	Default *Choice
 }
 
 type Choice struct {
 	Description string
-	Change      Change
+	// Key, if set, is the message key the description is translated from.
+	Key    string
+	Change Change
+	// Guard, if set, must pass for the choice to be offered.
+	Guard *Guard
+	// Branches are conditional changes. The first branch whose guard
+	// passes is applied instead of Change.
+	Branches []Branch
+	// Next, if set, is a follow-up decision offered right after the choice
+	// is made, within the same turn.
+	Next *Decision
+	// rule is the 1-based index of the rule that offered the choice, or 0
+	// if it wasn't offered by a rule.
+	rule int
+	// offered locates the choice among those offered by an Engine.
+	offered offerPosition
+}
+
+// offerPosition is the position of a choice among the offered decisions.
+type offerPosition struct {
+	// decision is the 1-based index of the decision, or 0 if the choice
+	// wasn't offered, and choice the index of the choice in the decision.
+	decision, choice int
+}
+
+// fromRule returns a copy of the decision marked as offered by rule at
+// index i.
+func (d Decision) fromRule(i int, rule Rule) Decision {
+	d.Rule = rule.Name
+	choices := make([]Choice, len(d.Choices))
+	for j, choice := range d.Choices {
+		choice.rule = i + 1
+		choices[j] = choice
+	}
+	d.Choices = choices
+	return d
+}
+
+// Branch is a change made by a choice only if its guard passes.
+type Branch struct {
+	Guard  Guard
+	Change Change
+}
+
+// change returns the change the choice makes to world: that of its first
+// branch whose guard passes or, if there's none, Change.
+func (c Choice) change(world World) (Change, error) {
+	for i, branch := range c.Branches {
+		pass, err := branch.Guard.Pass(world)
+		if err != nil {
+			return Change{}, fmt.Errorf("choice %v: branch %d: %v", c.Description, i, err)
+		}
+		if pass {
+			return branch.Change, nil
+		}
+	}
+	return c.Change, nil
+}
+
+// availableChoices returns a copy of the decision without the choices whose
+// guard doesn't pass in world.
+func (d Decision) availableChoices(world World) (Decision, error) {
+	choices := make([]Choice, 0, len(d.Choices))
+	for _, choice := range d.Choices {
+		if choice.Guard != nil {
+			pass, err := choice.Guard.Pass(world)
+			if err != nil {
+				return Decision{}, fmt.Errorf("choice %v: %v", choice.Description, err)
+			}
+			if !pass {
+				continue
+			}
+		}
+		choices = append(choices, choice)
+	}
+	d.Choices = choices
+	return d, nil
 }
 
 type Guard struct {
 	expr.Node
+	// Source is the expression the node was parsed from.
+	Source string
+	GuardOptions
+}
+
+// GuardOptions configure how a guard expression is compiled and evaluated.
+type GuardOptions struct {
+	// Funcs are the custom functions the expression may call.
+	Funcs Funcs
+	// FlatNames exposes each resource and power as a top-level variable, so
+	// "Money > 1000" can be written instead of "World.Resources.Money > 1000".
+	// Keys that aren't valid identifiers or clash with World or a function
+	// are skipped, and resources, fractional ones last, take precedence over
+	// powers of the same name. Such guards can't be type checked when parsed.
+	FlatNames bool
+}
+
+// Funcs maps names to custom functions callable from guard expressions,
+// e.g. "percent": func(x float64) float64 { ... }. Number literals are
+// passed as float64.
+type Funcs map[string]interface{}
+
+func NewGuard(source string) (Guard, error) {
+	return NewGuardWithOptions(source, GuardOptions{})
+}
+
+func NewGuardWithOptions(source string, opts GuardOptions) (Guard, error) {
+	var options []expr.OptionFn
+	if !opts.FlatNames {
+		options = append(options, expr.Define("World", World{}))
+		// Builtins are declared as interface{} rather than by their func
+		// type, which expr would take as the type of their results and
+		// refuse to compare with numbers.
+		for _, name := range builtinFuncs {
+			options = append(options, expr.Define(name, new(interface{})))
+		}
+		for name, fn := range opts.Funcs {
+			options = append(options, expr.Define(name, fn))
+		}
+	}
+	node, err := expr.Parse(source, options...)
+	if err != nil {
+		return Guard{}, err
+	}
+	// Negating the expression makes the type checker reject those known
+	// not to be booleans, e.g. numbers. Custom functions are declared as
+	// interface{} for that, like builtins, as their results would otherwise
+	// be taken as funcs.
+	if !opts.FlatNames {
+		negated := []expr.OptionFn{expr.Define("World", World{})}
+		for _, name := range builtinFuncs {
+			negated = append(negated, expr.Define(name, new(interface{})))
+		}
+		for name := range opts.Funcs {
+			negated = append(negated, expr.Define(name, new(interface{})))
+		}
+		if _, err := expr.Parse("not ("+source+")", negated...); err != nil {
+			return Guard{}, fmt.Errorf("guard %q does not evaluate to bool", source)
+		}
+	}
+	return Guard{Node: node, Source: source, GuardOptions: opts}, nil
 }
 
 func (g Guard) Pass(world World) (bool, error) {
-	out, err := expr.Run(g.Node, map[string]World{"World": world})
+	env := envPool.Get().(*guardEnv)
+	defer envPool.Put(env)
+	env.fill(g, world)
+	out, err := expr.Run(g.Node, env.vars)
 	if err != nil {
 		return false, err
 	}
-	return out.(bool), nil
+	pass, ok := out.(bool)
+	if !ok {
+		return false, fmt.Errorf("guard %q did not evaluate to bool, got %T", g.Source, out)
+	}
+	return pass, nil
+}
+
+// envPool holds environments reused across guard evaluations to spare the
+// garbage collector.
+var envPool = sync.Pool{
+	New: func() interface{} {
+		return newGuardEnv()
+	},
+}
+
+// guardEnv is the environment guards are evaluated in. Its builtins are
+// bound once to world, which is set before each evaluation.
+type guardEnv struct {
+	world World
+	vars  map[string]interface{}
+	// builtins are the values of the builtin functions in vars.
+	builtins map[string]interface{}
+}
+
+func newGuardEnv() *guardEnv {
+	env := &guardEnv{vars: make(map[string]interface{})}
+	env.builtins = builtins(&env.world)
+	return env
+}
+
+// fill replaces the contents of env with the variables and functions
+// visible to g evaluated against world.
+func (env *guardEnv) fill(g Guard, world World) {
+	for k := range env.vars {
+		delete(env.vars, k)
+	}
+	env.world = world
+	if g.FlatNames {
+		for _, values := range []map[string]int{world.Powers, world.Resources} {
+			for k, v := range values {
+				if isIdentifier(k) {
+					env.vars[k] = v
+				}
+			}
+		}
+		for k, v := range world.Reals {
+			if isIdentifier(k) {
+				env.vars[k] = v
+			}
+		}
+	}
+	for name, fn := range env.builtins {
+		env.vars[name] = fn
+	}
+	for name, fn := range g.Funcs {
+		env.vars[name] = fn
+	}
+	env.vars["World"] = world
+}
+
+var exprKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "matches": true,
+	"true": true, "false": true, "nil": true,
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || exprKeywords[s] {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
 }
 
 type Rule struct {
+	// Name identifies the rule within its scenario.
+	Name string
 	Guard
+	// Weight is the likelihood of the decision being offered, in [0, 1].
+	// Rules with a negative weight, down to -1, are never offered: while
+	// their guard passes, they subtract their weight from that of the
+	// other rules of their Group instead, e.g. to make two decisions
+	// mutually exclusive.
 	Weight float64
+	// Group, if set, makes the rule mutually exclusive with the other
+	// rules of the group: at most one of them, the best ranked passing one,
+	// mandatory ones first, is offered per turn. Negative weights suppress
+	// the rest of the group.
+	Group string
 	Decision
+	// Cooldown is the number of turns after its decision is chosen before
+	// the rule can be offered again.
+	Cooldown int
+	// Once rules are never offered again after their decision is chosen.
+	Once bool
+	// Mandatory rules are always offered when their guard passes, ahead of
+	// the others, regardless of weight, unless it's negative.
+	Mandatory bool
+	// Priority orders decisions of equal weight, highest first.
+	Priority int
+	// WeightExpr, if set, computes the weight from the world instead of
+	// Weight. It's clamped to [-1, 1].
+	WeightExpr *NumberExpr
 }
 
-func NewRule(guard string, weight float64, decision Decision) (Rule, error) {
-	node, err := expr.Parse(guard, expr.Define("World", World{}))
+func NewRule(name string, guard string, weight float64, decision Decision) (Rule, error) {
+	return NewRuleWithOptions(name, guard, weight, decision, GuardOptions{})
+}
+
+// NewRuleWithOptions creates a rule whose guard is compiled with opts.
+func NewRuleWithOptions(name string, guard string, weight float64, decision Decision, opts GuardOptions) (Rule, error) {
+	g, err := NewGuardWithOptions(guard, opts)
 	if err != nil {
 		return Rule{}, err
 	}
 
 	return Rule{
-		Guard:    Guard{node},
+		Name:     name,
+		Guard:    g,
 		Weight:   weight,
 		Decision: decision,
 	}, nil
 }
 
 func (r Rule) Evaluate(world World) (float64, error) {
+	pass, weight, err := r.evaluate(world)
+	if err != nil || !pass {
+		return 0, err
+	}
+	return weight, nil
+}
+
+// evaluate returns whether the rule's guard passes and the rule's weight.
+// The weight is only computed from WeightExpr if the guard passes.
+func (r Rule) evaluate(world World) (bool, float64, error) {
 	pass, err := r.Guard.Pass(world)
 	if err != nil {
-		return 0, err
+		return false, 0, fmt.Errorf("rule %v: %v", r.Name, err)
 	}
-	if !pass {
-		return 0, nil
+	if !pass || r.WeightExpr == nil {
+		return pass, r.Weight, nil
 	}
-	return r.Weight, nil
+	weight, err := r.WeightExpr.Eval(world)
+	if err != nil {
+		return false, 0, fmt.Errorf("rule %v: weight: %v", r.Name, err)
+	}
+	return pass, math.Max(-1, math.Min(1, weight)), nil
 }
 
 type Scenario struct {
 	Rules []Rule
+	// Mode selects how decisions are picked from passing rules.
+	Mode SelectionMode
+	// Selector, if set, picks decisions instead of Mode.
+	Selector Selector
+	// Parallel evaluates guards concurrently, which pays off for scenarios
+	// with hundreds of rules. Custom guard functions must then be safe for
+	// concurrent use.
+	Parallel bool
+	// Meta describes how resources and powers are displayed.
+	Meta ResourceMeta
+	// Scheduled events are offered on their turns ahead of any rule.
+	Scheduled []ScheduledEvent
+	// InitialWorld is the world games start in. If nil, they start with
+	// 4000 Money, 90 Military and 10 Legislation.
+	InitialWorld *World
+	// Decay changes resources and powers at the end of every turn,
+	// regardless of the choices made, e.g. {"Popularity": {0.95, 0}}.
+	Decay map[string]Delta
+	// Renamed maps resources and powers renamed since a previous version
+	// of the scenario from their old to their new name, so that games saved
+	// with that version can still be loaded.
+	Renamed map[string]string
+	// AlwaysAvailable are persistent actions, e.g. "End Game", offered
+	// every turn after the other decisions, as long as the game goes on.
+	// They don't count against GameConfig.MaxDecisions.
+	AlwaysAvailable []Decision
+	// DynamicKeys lets choices change resources and powers missing from
+	// the initial world and Meta, which Validate reports otherwise.
+	DynamicKeys bool
+}
+
+// initialWorld returns a copy of the world games of s start in.
+func (s Scenario) initialWorld() World {
+	if s.InitialWorld != nil {
+		world := s.InitialWorld.Copy()
+		if world.Resources == nil {
+			world.Resources = make(map[string]int)
+		}
+		if world.Powers == nil {
+			world.Powers = make(map[string]int)
+		}
+		return world
+	}
+	return World{
+		Resources: map[string]int{
+			"Money": 4000,
+		},
+		Powers: map[string]int{
+			"Military":    90,
+			"Legislation": 10,
+		},
+	}
 }
 
 type CandidateDecision struct {
-	Weight float64
+	// Rule is the name of the rule the decision originates from.
+	Rule     string
+	Weight   float64
+	Priority int
+	// Group is the group of the rule, if any.
+	Group string
 	Decision
 }
 
+// RuleState tracks rules fired during a game.
+type RuleState struct {
+	// LastFired maps a rule index to the turn its decision was last chosen.
+	LastFired map[int]int
+	// Fires maps a rule index to the number of times its decision was
+	// chosen.
+	Fires map[int]int
+	// Chosen lists the choices made, oldest first.
+	Chosen []ChosenChoice
+}
+
+// ChosenChoice records a choice made.
+type ChosenChoice struct {
+	// Rule is the name of the rule that offered the choice, if any.
+	Rule   string `json:"rule,omitempty"`
+	Choice string `json:"choice"`
+}
+
+func NewRuleState() RuleState {
+	return RuleState{
+		LastFired: make(map[int]int),
+		Fires:     make(map[int]int),
+	}
+}
+
+func (s RuleState) Copy() RuleState {
+	copy := NewRuleState()
+	for i, turn := range s.LastFired {
+		copy.LastFired[i] = turn
+	}
+	for i, n := range s.Fires {
+		copy.Fires[i] = n
+	}
+	copy.Chosen = append([]ChosenChoice(nil), s.Chosen...)
+	return copy
+}
+
+// Available reports whether the rule at index i can be offered on turn,
+// i.e. it has cooled down and, if it's a one-shot rule, hasn't fired yet.
+func (s RuleState) Available(i int, rule Rule, turn int) bool {
+	last, ok := s.LastFired[i]
+	if !ok {
+		return true
+	}
+	return !rule.Once && turn-last >= rule.Cooldown
+}
+
+// Fired records that choice was chosen on turn.
+func (s RuleState) Fired(choice Choice, turn int) {
+	if choice.rule == 0 {
+		return
+	}
+	s.LastFired[choice.rule-1] = turn
+	s.Fires[choice.rule-1]++
+}
+
 type CandidateRanking []CandidateDecision
 
 func (c CandidateRanking) Len() int {
@@ -104,88 +650,325 @@ func (c CandidateRanking) Swap(i, j int) {
 	c[i], c[j] = c[j], c[i]
 }
 
+// Less ranks the highest-weight decisions first, breaking ties by highest
+// priority and then by rule name so that the order is deterministic.
 func (c CandidateRanking) Less(i, j int) bool {
-	return c[i].Weight < c[j].Weight
+	if c[i].Weight != c[j].Weight {
+		return c[i].Weight > c[j].Weight
+	}
+	if c[i].Priority != c[j].Priority {
+		return c[i].Priority > c[j].Priority
+	}
+	return c[i].Rule < c[j].Rule
 }
 
 type Rand interface {
 	Float64() float64
 }
 
+// SafeRand is a Rand safe for concurrent use.
+type SafeRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func NewSafeRand(seed int64) *SafeRand {
+	return &SafeRand{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (r *SafeRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()
+}
+
+// DecisionsF returns the decisions offered for world. The limit is
+// inclusive: at most maxNumDecisions decisions are returned.
 type DecisionsF func(world World, maxNumDecisions int) ([]Decision, error)
 
-func (s Scenario) Decisions(r Rand) DecisionsF {
+// Decisions selects decisions from rules that have cooled down according to
+// state, after those of the scheduled events due.
+func (s Scenario) Decisions(r Rand, state RuleState) DecisionsF {
 	return func(world World, maxNumDecisions int) ([]Decision, error) {
-		candidates := make([]CandidateDecision, len(s.Rules))
-		for i, rule := range s.Rules {
-			weight, err := rule.Evaluate(world)
+		return s.decisions(r, state, world, maxNumDecisions, timeSpan{}, nil)
+	}
+}
+
+// decisions implements Decisions, explaining the selection in trace unless
+// it's nil. Time-based scheduled events are offered if their time came
+// during span.
+func (s Scenario) decisions(r Rand, state RuleState, world World, maxNumDecisions int, span timeSpan, trace *DecisionTrace) ([]Decision, error) {
+	scheduled, err := s.dueDecisions(world, span)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make([]int, 0, len(s.Rules))
+	for i, rule := range s.Rules {
+		if state.Available(i, rule, world.Turn) {
+			available = append(available, i)
+		}
+	}
+
+	evaluations := s.evaluate(available, world)
+	// suppression is the sum of the negative weights of the passing rules
+	// of each group.
+	suppression := make(map[string]float64)
+	for j, evaluation := range evaluations {
+		if evaluation.err != nil {
+			return nil, evaluation.err
+		}
+		rule := s.Rules[available[j]]
+		if evaluation.pass && evaluation.weight < 0 && rule.Group != "" {
+			suppression[rule.Group] += evaluation.weight
+		}
+	}
+
+	var mandatory, candidates []CandidateDecision
+	for j, evaluation := range evaluations {
+		i := available[j]
+		rule := s.Rules[i]
+		decision := rule.Decision.fromRule(i, rule)
+		if evaluation.pass {
+			var err error
+			decision, err = decision.availableChoices(world)
 			if err != nil {
 				return nil, err
 			}
-			candidates[i] = CandidateDecision{
-				Weight:   weight,
-				Decision: rule.Decision,
+			if len(decision.Choices) == 0 {
+				continue
+			}
+		}
+		candidate := CandidateDecision{
+			Rule:     rule.Name,
+			Weight:   evaluation.weight,
+			Priority: rule.Priority,
+			Decision: decision,
+			Group:    rule.Group,
+		}
+		if rule.Mandatory {
+			if evaluation.pass && candidate.Weight >= 0 {
+				mandatory = append(mandatory, candidate)
 			}
+			continue
+		}
+		if !evaluation.pass || candidate.Weight < 0 {
+			candidate.Weight = 0
+		} else if rule.Group != "" {
+			candidate.Weight = math.Max(0, candidate.Weight+suppression[rule.Group])
+		}
+		candidates = append(candidates, candidate)
+	}
+	// Sorting stably keeps remaining ties, e.g. between unnamed rules, in
+	// the order of the rules.
+	sort.Stable(CandidateRanking(mandatory))
+	sort.Stable(CandidateRanking(candidates))
+	// Only the best ranked candidate of each group may be offered,
+	// mandatory ones first. The others are dropped rather than zeroed so
+	// that selectors stopping at the first unlikely candidate still see
+	// those ranked after them.
+	grouped := make(map[string]bool)
+	best := func(candidate CandidateDecision) bool {
+		if candidate.Group == "" {
+			return true
+		}
+		if grouped[candidate.Group] {
+			return false
+		}
+		grouped[candidate.Group] = true
+		return true
+	}
+	kept := mandatory[:0]
+	for _, candidate := range mandatory {
+		if best(candidate) {
+			kept = append(kept, candidate)
+		}
+	}
+	mandatory = kept
+	kept = candidates[:0]
+	for _, candidate := range candidates {
+		if candidate.Weight <= 0 || best(candidate) {
+			kept = append(kept, candidate)
 		}
-		ranking := CandidateRanking(candidates)
-		sort.Sort(ranking)
+	}
+	candidates = kept
 
-		decisions := make([]Decision, 0, len(candidates))
-		for _, candidate := range candidates {
-			if r.Float64() < candidate.Weight {
-				decisions = append(decisions, candidate.Decision)
-				if len(decisions) > maxNumDecisions {
-					break
-				}
+	decisions := make([]Decision, 0, maxNumDecisions)
+	for _, decision := range scheduled {
+		if len(decisions) >= maxNumDecisions {
+			break
+		}
+		decisions = append(decisions, decision)
+	}
+	for _, candidate := range mandatory {
+		if len(decisions) >= maxNumDecisions {
+			break
+		}
+		decisions = append(decisions, candidate.Decision)
+	}
+	var selector Selector = s.Mode
+	if s.Selector != nil {
+		selector = s.Selector
+	}
+	if trace == nil {
+		selected := selector.Select(candidates, maxNumDecisions-len(decisions), r)
+		return append(decisions, selected...), nil
+	}
+	recording := &recordingRand{Rand: r}
+	selected := selector.Select(candidates, maxNumDecisions-len(decisions), recording)
+	decisions = append(decisions, selected...)
+	_, probabilistic := selector.(probabilitySelector)
+	*trace = newDecisionTrace(mandatory, candidates, decisions, recording.draws, probabilistic || selector == Selector(Independent))
+	return decisions, nil
+}
+
+// Apply applies the choice's change to the world. r is used to sample
+// random deltas and may be nil if the change has none. If it fails, the
+// world may be partly changed; see ApplyAll.
+func (w *World) Apply(choice Choice, r Rand) error {
+	change, err := choice.change(*w)
+	if err != nil {
+		return err
+	}
+	if r == nil && change.random() {
+		return fmt.Errorf("choice %v has random deltas but no Rand was given", choice.Description)
+	}
+	before := World{Resources: copyValues(w.Resources), Powers: copyValues(w.Powers)}
+	for resource, delta := range change.Resources {
+		if value, ok := w.Reals[resource]; ok {
+			value = delta.apply(value, r)
+			if err := w.checkBounds(resource, value); err != nil {
+				return err
 			}
+			w.Reals[resource] = w.clampReal(resource, value)
+			continue
 		}
-		return decisions, nil
+		value := updatedValue(w.Resources[resource], delta, r, w.rounding(resource))
+		if err := w.checkBounds(resource, float64(value)); err != nil {
+			return err
+		}
+		w.Resources[resource] = w.clamp(resource, value)
+	}
+	for power, delta := range change.Powers {
+		value := updatedValue(w.Powers[power], delta, r, w.rounding(power))
+		if err := w.checkBounds(power, float64(value)); err != nil {
+			return err
+		}
+		w.Powers[power] = w.clamp(power, value)
 	}
+	if err := w.updateDerived(); err != nil {
+		return err
+	}
+	w.recordChange(before)
+	return nil
 }
 
-func (w *World) Apply(choice Choice) error {
-	for resource, delta := range choice.Change.Resources {
-		w.Resources[resource] = updatedValue(w.Resources[resource], delta)
+// ApplyAll applies choices in order like Apply, but atomically: if one
+// fails, e.g. because StrictBounds is set and it pushes a value out of
+// bounds, the world is left unchanged and the error identifies the choice.
+func (w *World) ApplyAll(choices []Choice, r Rand) error {
+	world := w.Copy()
+	for i, choice := range choices {
+		if err := world.Apply(choice, r); err != nil {
+			return fmt.Errorf("choice %d (%v): %v", i, choice.Description, err)
+		}
+	}
+	*w = world
+	return nil
+}
+
+// checkBounds returns an error if StrictBounds is set and value is out of
+// the bounds of key.
+func (w World) checkBounds(key string, value float64) error {
+	if !w.StrictBounds {
+		return nil
 	}
-	for power, delta := range choice.Change.Powers {
-		w.Powers[power] = updatedValue(w.Powers[power], delta)
+	if message := w.outOfBounds(key, value); message != "" {
+		return errors.New(message)
 	}
 	return nil
 }
 
-func updatedValue(old int, delta Delta) int {
-	return int(math.Round(delta[0]*float64(old) + delta[1]))
+// recordChange sets LastChange to the difference between w and before for
+// every resource and power, including unchanged ones so that guards can
+// refer to them.
+func (w *World) recordChange(before World) {
+	w.LastChange = make(map[string]int, len(w.Resources)+len(w.Powers))
+	for _, values := range []struct{ old, new map[string]int }{
+		{before.Powers, w.Powers},
+		{before.Resources, w.Resources},
+	} {
+		for key, value := range values.new {
+			w.LastChange[key] = value - values.old[key]
+		}
+	}
 }
 
-func gameLoop(scenario Scenario, choiceCh <-chan Choice) (<-chan []Decision, <-chan World, error) {
-	world := World{
-		Resources: map[string]int{
-			"Money": 4000,
-		},
-		Powers: map[string]int{
-			"Military":    90,
-			"Legislation": 10,
-		},
+func (w World) clamp(key string, value int) int {
+	if bounds, ok := w.Bounds[key]; ok {
+		if value < bounds[0] {
+			value = bounds[0]
+		}
+		if value > bounds[1] {
+			value = bounds[1]
+		}
+	}
+	if w.NonNegative[key] && value < 0 {
+		value = 0
+	}
+	return value
+}
+
+// clampReal is clamp for fractional resources.
+func (w World) clampReal(key string, value float64) float64 {
+	if bounds, ok := w.Bounds[key]; ok {
+		value = math.Max(float64(bounds[0]), math.Min(float64(bounds[1]), value))
+	}
+	if w.NonNegative[key] && value < 0 {
+		value = 0
+	}
+	return value
+}
+
+func updatedValue(old int, delta Delta, r Rand, mode RoundingMode) int {
+	return mode.round(delta.apply(float64(old), r))
+}
+
+// gameLoop runs a game in the background, sending the world and the offered
+// decisions each turn and reading choices from choiceCh. If a choice can't
+// be applied, the error is sent on the error channel and the game stops.
+func gameLoop(scenario Scenario, cfg GameConfig, choiceCh <-chan Choice) (<-chan []Decision, <-chan World, <-chan GameResult, <-chan error, error) {
+	engine, err := NewEngine(scenario, cfg)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	decisionCh := make(chan []Decision)
 	worldCh := make(chan World)
+	resultCh := make(chan GameResult, 1)
+	errCh := make(chan error, 1)
 
 	go func() {
 		defer close(decisionCh)
 		defer close(worldCh)
+		defer close(resultCh)
+		defer close(errCh)
+
+		var world World
+		var decisions []Decision
+		unsubscribe := engine.Subscribe(func(w World, d []Decision) {
+			world, decisions = w, d
+		})
+		defer unsubscribe()
 
-		r := rand.New(rand.NewSource(0))
 		for {
 			worldCh <- world
 
-			decisions, err := scenario.Decisions(r)(world, 3)
-			if err != nil {
-				log.Fatalf("Error getting decisions: %v", err)
+			if result := engine.Result(); result != nil {
+				resultCh <- *result
+				return
 			}
 			if len(decisions) == 0 {
-				// TODO: We need to figure out how to solve this; the game can get stuck.
-				// So simple continue won't be enough.
 				return
 			}
 
@@ -195,23 +978,89 @@ func gameLoop(scenario Scenario, choiceCh <-chan Choice) (<-chan []Decision, <-c
 			if !ok {
 				return
 			}
-			err = world.Apply(choice)
+			err := engine.Choose(choice)
 			if err != nil {
-				log.Printf("Error applying choice %v to world: %v", choice.Description, err)
+				errCh <- fmt.Errorf("choosing %v: %v", choice.Description, err)
 				return
 			}
 		}
 	}()
 
-	return decisionCh, worldCh, nil
+	return decisionCh, worldCh, resultCh, errCh, nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		if !runSpecs(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) == 3 && os.Args[1] == "replay" {
+		if err := replaySpec(os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "politika: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	scenario, err := opts.scenario()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "politika: %v\n", err)
+		os.Exit(1)
+	}
+	cfg := opts.gameConfig()
+
+	switch opts.UI {
+	case "web":
+		engine, err := NewEngine(scenario, cfg)
+		if err != nil {
+			log.Fatalf("Error starting game: %v", err)
+		}
+		log.Fatal(serveHTTP(engine, opts.Addr))
+	case "protocol":
+		engine, err := NewEngine(scenario, cfg)
+		if err != nil {
+			log.Fatalf("Error starting game: %v", err)
+		}
+		if err := runProtocol(engine, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		choiceCh := make(chan Choice)
+		decisionCh, worldCh, resultCh, errCh, err := gameLoop(scenario, cfg, choiceCh)
+		if err != nil {
+			log.Fatalf("Error starting game loop: %v", err)
+		}
+		go func() {
+			for err := range errCh {
+				log.Fatalf("Error: %v", err)
+			}
+		}()
+
+		consoleUI(decisionCh, worldCh, resultCh, choiceCh, consoleOptions{
+			Meta:           scenario.Meta,
+			Theme:          defaultTheme,
+			Locale:         "en",
+			BudgetResource: cfg.BudgetResource,
+		})
+	}
+}
+
+// defaultScenario is the scenario played when no scenario file is given.
+func defaultScenario() (Scenario, error) {
 	rule1, err := NewRule(
+		"putsch",
 		"World.Resources.Money > 1000 and World.Powers.Military >= 90",
 		1.0,
-		Decision{"Make putsch",
-			[]Choice{
+		Decision{
+			Description: "Make putsch",
+			Choices: []Choice{
 				{
 					Description: "Accept",
 					Change: Change{
@@ -227,92 +1076,116 @@ func main() {
 					Description: "Reject",
 					Change: Change{
 						Powers: map[string]Delta{
-							"Military": Delta{0.1, 0},
+							"Military": ScaleTo(0.1),
 						},
 					},
 				},
 			},
 		},
 	)
+	if err != nil {
+		return Scenario{}, err
+	}
 	rule2, err := NewRule(
+		"quit",
 		"true",
 		1.0,
-		Decision{"Quit",
-			[]Choice{
+		Decision{
+			Description: "Quit",
+			Choices: []Choice{
 				{
 					Description: "Accept",
 				},
 			}})
 	if err != nil {
-		log.Fatalf("Error parsing expression: %v", err)
+		return Scenario{}, err
 	}
 
-	scenario := Scenario{
+	return Scenario{
 		Rules: []Rule{rule1, rule2},
-	}
-
-	choiceCh := make(chan Choice)
-	decisionCh, worldCh, err := gameLoop(scenario, choiceCh)
-	if err != nil {
-		log.Fatalf("Error starting game loop: %v", err)
-	}
-
-	consoleUI(decisionCh, worldCh, choiceCh)
+		InitialWorld: &World{
+			Resources: map[string]int{"Money": 4000, "Popularity": 0},
+			Powers:    map[string]int{"Military": 90, "Legislation": 10},
+		},
+		Meta: ResourceMeta{
+			"Money":    Currency,
+			"Military": Percent,
+		},
+	}, nil
 }
 
-func consoleUI(decisionCh <-chan []Decision, worldCh <-chan World, choiceCh chan<- Choice) {
-	debugWindow := tui.NewLabel("")
-	choiceTable := tui.NewTable(0, 0)
-	powerStatus := tui.NewStatusBar("")
-	resourceStatus := tui.NewStatusBar("")
-	root := tui.NewVBox(
-		tui.NewHBox(
-			tui.NewVBox(
-				choiceTable,
-				tui.NewSpacer(),
-			),
-			debugWindow),
-		tui.NewSpacer(),
-		tui.NewHBox(
-			tui.NewVBox(
-				resourceStatus,
-				powerStatus,
-			),
-			tui.NewVBox(
-				tui.NewSpacer(),
-				tui.NewHBox(
-					tui.NewSpacer(),
-					tui.NewLabel("ESC to quit"),
-				),
-			),
-		),
-	)
+// consoleOptions configures the console UI.
+type consoleOptions struct {
+	Meta  ResourceMeta
+	Theme Theme
+	// Decisions and choices are shown translated to Locale by Translator.
+	Translator Translator
+	Locale     string
+	// BudgetResource is the resource unaffordable choices would overspend;
+	// see GameConfig.
+	BudgetResource string
+}
 
-	choiceTable.SetFocused(true)
-	ui, err := tui.New(root)
+// consoleUI runs the console UI. The choices sent on choiceCh are the
+// untranslated ones; unaffordable choices can't be selected. Once the game
+// ends, a game over panel is shown until a key is pressed.
+func consoleUI(decisionCh <-chan []Decision, worldCh <-chan World, resultCh <-chan GameResult, choiceCh chan<- Choice, opts consoleOptions) {
+	panels := newGamePanels("ESC to quit")
+	choiceTable := panels.choiceTable
+	ui, err := tui.New(panels.root)
 	if err != nil {
 		log.Fatal(err)
 	}
+	ui.SetTheme(opts.Theme.tuiTheme())
 
 	wait := sync.WaitGroup{}
 
+	// current is the latest world; it's only accessed in ui.Update.
+	var current World
+	// offered are the decisions displayed; it's only accessed in ui.Update
+	// and key handlers.
+	var offered []Decision
+	// choose sends the choice numbered index across the offered decisions,
+	// unless there's no such choice or it's unaffordable.
+	choose := func(index int) {
+		choice, ok := choiceAt(offered, index)
+		if ok && affordable(current, choice, opts.BudgetResource) {
+			choiceCh <- choice
+		}
+	}
+	choiceTable.OnItemActivated(func(t *tui.Table) {
+		choose(t.Selected())
+	})
+	// quitting is closed when the player quits; ui.Update mustn't be called
+	// after that as it would block.
+	quitting := make(chan struct{})
+
 	wait.Add(1)
 	go func() {
 		defer wait.Done()
+		var last World
 		for world := range worldCh {
+			last = world
 			ui.Update(func() {
-				powers := make([]string, 0)
-				for k, v := range world.Powers {
-					powers = append(powers, fmt.Sprintf("%v: %v", k, v))
-				}
-				powerStatus.SetText(strings.Join(powers, " "))
-				resources := make([]string, 0)
-				for k, v := range world.Resources {
-					resources = append(resources, fmt.Sprintf("%v: %v", k, v))
-				}
-				resourceStatus.SetText(strings.Join(resources, " "))
+				current = world
+				panels.showWorld(world, opts)
 			})
 		}
+
+		var result *GameResult
+		if r, ok := <-resultCh; ok {
+			result = &r
+		}
+		select {
+		case <-quitting:
+			return
+		default:
+		}
+		ui.Update(func() {
+			offered = nil
+			ui.SetWidget(gameOverPanel(gameOverText(result, last, opts.Meta)))
+			ui.SetKeybinding("Enter", ui.Quit)
+		})
 	}()
 
 	wait.Add(1)
@@ -321,30 +1194,21 @@ func consoleUI(decisionCh <-chan []Decision, worldCh <-chan World, choiceCh chan
 
 		for decisions := range decisionCh {
 			ui.Update(func() {
-				debugWindow.SetText(spew.Sdump(decisions))
-				choiceTable.RemoveRows()
-
-				choices := make([]Choice, 0)
-
-				for _, decision := range decisions {
-					label := tui.NewLabel(decision.Description)
-					for _, choice := range decision.Choices {
-						choiceBtn := tui.NewLabel(choice.Description)
-						choiceTable.AppendRow(label, choiceBtn)
-						choices = append(choices, choice)
-					}
-				}
-
-				choiceTable.OnItemActivated(func(t *tui.Table) {
-					if t.Selected() >= 0 && t.Selected() < len(choices) {
-						choiceCh <- choices[t.Selected()]
-					}
-				})
+				offered = decisions
+				panels.showDecisions(decisions, current, opts)
 			})
 		}
 	}()
 
-	ui.SetKeybinding("Esc", func() { close(choiceCh); ui.Quit() })
+	for i := 1; i <= 9; i++ {
+		index := i - 1
+		ui.SetKeybinding(strconv.Itoa(i), func() { choose(index) })
+	}
+	ui.SetKeybinding("Esc", func() {
+		close(quitting)
+		close(choiceCh)
+		ui.Quit()
+	})
 
 	if err := ui.Run(); err != nil {
 		log.Fatal(err)
@@ -352,3 +1216,163 @@ func consoleUI(decisionCh <-chan []Decision, worldCh <-chan World, choiceCh chan
 
 	wait.Wait()
 }
+
+// replayUI shows a recorded game in the console, stepping through its
+// choices with the left and right arrow keys.
+func replayUI(scenario Scenario, seed int64, choices []Choice) {
+	opts := consoleOptions{
+		Meta:   scenario.Meta,
+		Theme:  defaultTheme,
+		Locale: "en",
+	}
+	panels := newGamePanels("")
+	ui, err := tui.New(panels.root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ui.SetTheme(opts.Theme.tuiTheme())
+
+	step := 0
+	show := func() {
+		world, decisions, err := replayAt(scenario, seed, choices, step)
+		if err != nil {
+			panels.debugWindow.SetText(err.Error())
+			return
+		}
+		panels.showWorld(world, opts)
+		panels.showDecisions(decisions, world, opts)
+		panels.hint.SetText(fmt.Sprintf("Step %d/%d, turn %d. LEFT/RIGHT to step, ESC to quit", step, len(choices), world.Turn))
+	}
+	show()
+	ui.SetKeybinding("Left", func() {
+		if step > 0 {
+			step--
+			show()
+		}
+	})
+	ui.SetKeybinding("Right", func() {
+		if step < len(choices) {
+			step++
+			show()
+		}
+	})
+	ui.SetKeybinding("Esc", ui.Quit)
+
+	if err := ui.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// gamePanels are the widgets of the console UI showing a game: the offered
+// decisions, a debug window and status bars.
+type gamePanels struct {
+	root           *tui.Box
+	hint           *tui.Label
+	debugWindow    *tui.Label
+	choiceTable    *tui.Table
+	powerStatus    *tui.Box
+	resourceStatus *tui.Box
+	warningLabel   *tui.Label
+}
+
+// newGamePanels lays out the panels, showing hint in the bottom right
+// corner, e.g. "ESC to quit".
+func newGamePanels(hint string) *gamePanels {
+	p := &gamePanels{
+		debugWindow:    tui.NewLabel(""),
+		choiceTable:    tui.NewTable(0, 0),
+		powerStatus:    tui.NewHBox(),
+		resourceStatus: tui.NewHBox(),
+		warningLabel:   tui.NewLabel(""),
+		hint:           tui.NewLabel(hint),
+	}
+	p.warningLabel.SetStyleName("warning")
+	p.root = tui.NewVBox(
+		tui.NewHBox(
+			tui.NewVBox(
+				p.choiceTable,
+				tui.NewSpacer(),
+			),
+			p.debugWindow),
+		tui.NewSpacer(),
+		tui.NewHBox(
+			tui.NewVBox(
+				p.warningLabel,
+				p.resourceStatus,
+				p.powerStatus,
+			),
+			tui.NewVBox(
+				tui.NewSpacer(),
+				tui.NewHBox(
+					tui.NewSpacer(),
+					p.hint,
+				),
+			),
+		),
+	)
+	p.choiceTable.SetFocused(true)
+	return p
+}
+
+// showWorld displays world in the status bars.
+func (p *gamePanels) showWorld(world World, opts consoleOptions) {
+	// Values changed by the last choice are highlighted until the next
+	// one.
+	styles := changeStyles(world.LastChange)
+	powers := make(map[string]string)
+	for k, v := range world.Powers {
+		powers[k] = fmt.Sprintf("%v: %v", k, FormatValue(k, v, opts.Meta))
+	}
+	setStatus(p.powerStatus, powers, styles)
+	resources := make(map[string]string)
+	for k, v := range world.Resources {
+		resources[k] = fmt.Sprintf("%v: %v", k, FormatValue(k, v, opts.Meta))
+	}
+	for k, v := range world.Reals {
+		resources[k] = fmt.Sprintf("%v: %v", k, FormatReal(k, v, opts.Meta))
+	}
+	setStatus(p.resourceStatus, resources, styles)
+	warnings := warningKeys(world, opts.Theme.LowThresholds)
+	if len(warnings) > 0 {
+		p.warningLabel.SetText("Low: " + strings.Join(warnings, ", "))
+	} else {
+		p.warningLabel.SetText("")
+	}
+}
+
+// showDecisions lists the choices of decisions, previewing their effect on
+// world.
+func (p *gamePanels) showDecisions(decisions []Decision, world World, opts consoleOptions) {
+	p.debugWindow.SetText(spew.Sdump(decisions))
+	p.choiceTable.RemoveRows()
+	for _, decision := range decisions {
+		localized := decision.Localized(opts.Translator, opts.Locale)
+		label := tui.NewLabel(localized.Description)
+		for i, choice := range decision.Choices {
+			choiceBtn := tui.NewLabel(localized.Choices[i].Description)
+			preview := tui.NewLabel(formatPreview(choice.Preview(world)))
+			canAfford := affordable(world, choice, opts.BudgetResource)
+			if !canAfford {
+				choiceBtn.SetStyleName("unaffordable")
+				preview.SetStyleName("unaffordable")
+			}
+			p.choiceTable.AppendRow(label, choiceBtn, preview)
+		}
+	}
+}
+
+// gameOverPanel centers text in a bordered "Game Over" box.
+func gameOverPanel(text string) tui.Widget {
+	panel := tui.NewVBox(
+		tui.NewLabel(text),
+		tui.NewSpacer(),
+		tui.NewLabel("ENTER to quit"),
+	)
+	panel.SetBorder(true)
+	panel.SetTitle("Game Over")
+	return tui.NewVBox(
+		tui.NewSpacer(),
+		tui.NewHBox(tui.NewSpacer(), panel, tui.NewSpacer()),
+		tui.NewSpacer(),
+	)
+}
@@ -0,0 +1,1090 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fixedRand is a Rand always returning the same number.
+type fixedRand float64
+
+func (r fixedRand) Float64() float64 {
+	return float64(r)
+}
+
+func mustRule(t testing.TB, name string, guard string, weight float64, decision Decision) Rule {
+	t.Helper()
+	rule, err := NewRule(name, guard, weight, decision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+// descriptions returns the descriptions of decisions.
+func descriptions(decisions []Decision) []string {
+	got := make([]string, len(decisions))
+	for i, decision := range decisions {
+		got[i] = decision.Description
+	}
+	return got
+}
+
+func TestDecisionsLimit(t *testing.T) {
+	var scenario Scenario
+	for i := 0; i < 10; i++ {
+		decision := Decision{Description: fmt.Sprintf("Decision %d", i), Choices: []Choice{{Description: "Accept"}}}
+		scenario.Rules = append(scenario.Rules, mustRule(t, decision.Description, "true", 1, decision))
+	}
+	decisions := scenario.Decisions(fixedRand(0), NewRuleState())
+	tests := []struct {
+		max  int
+		want int
+	}{
+		{0, 0},
+		{1, 1},
+		{3, 3},
+		{10, 10},
+		{12, 10},
+	}
+	for _, test := range tests {
+		got, err := decisions(World{}, test.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != test.want {
+			t.Errorf("max %v: got %v decisions, want %v", test.max, len(got), test.want)
+		}
+	}
+}
+
+func TestDecisionsRanking(t *testing.T) {
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "low", "true", 0.2, Decision{Description: "Low", Choices: []Choice{{Description: "Accept"}}}),
+		mustRule(t, "high", "true", 0.9, Decision{Description: "High", Choices: []Choice{{Description: "Accept"}}}),
+		mustRule(t, "medium", "true", 0.5, Decision{Description: "Medium", Choices: []Choice{{Description: "Accept"}}}),
+	}}
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		{1, []string{"High"}},
+		{2, []string{"High", "Medium"}},
+		{3, []string{"High", "Medium", "Low"}},
+	}
+	for _, test := range tests {
+		// 0.1 is below every weight, so all decisions pass the draw.
+		decisions, err := scenario.Decisions(fixedRand(0.1), NewRuleState())(World{}, test.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("max %v: got %v, want %v", test.max, got, test.want)
+		}
+	}
+}
+
+func TestDecisionsTieBreak(t *testing.T) {
+	rule := func(name string, weight float64, priority int) Rule {
+		r := mustRule(t, name, "true", weight, Decision{Description: name, Choices: []Choice{{Description: "Accept"}}})
+		r.Priority = priority
+		return r
+	}
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  []string
+	}{
+		{"by name", []Rule{rule("c", 0.5, 0), rule("a", 0.5, 0), rule("b", 0.5, 0)}, []string{"a", "b", "c"}},
+		{"by priority", []Rule{rule("a", 0.5, 1), rule("b", 0.5, 3), rule("c", 0.5, 2)}, []string{"b", "c", "a"}},
+		{"priority then name", []Rule{rule("c", 0.5, 1), rule("b", 0.5, 0), rule("a", 0.5, 1)}, []string{"a", "c", "b"}},
+		{"weight first", []Rule{rule("a", 0.4, 9), rule("b", 0.5, 0), rule("c", 0.5, 1)}, []string{"c", "b", "a"}},
+	}
+	for _, test := range tests {
+		scenario := Scenario{Rules: test.rules}
+		for i := 0; i < 5; i++ {
+			decisions, err := scenario.Decisions(fixedRand(0), NewRuleState())(World{}, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestWeightExpr(t *testing.T) {
+	weight, err := NewNumberExpr("1 - World.Resources.Money / 10000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := mustRule(t, "bankruptcy", "World.Resources.Money < 8000", 0.3, Decision{})
+	rule.WeightExpr = weight
+	tests := []struct {
+		money int
+		want  float64
+	}{
+		{7000, 0.3},
+		{2500, 0.75},
+		{0, 1},
+		{-5000, 1},
+		{9000, 0},
+	}
+	for _, test := range tests {
+		got, err := rule.Evaluate(World{Resources: map[string]int{"Money": test.money}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Money %v: got weight %v, want %v", test.money, got, test.want)
+		}
+	}
+
+	if _, err := NewNumberExpr("1 -"); err == nil {
+		t.Errorf("got no error for an invalid weight expression")
+	}
+}
+
+func TestGameLoopFallbackDecision(t *testing.T) {
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "never", "false", 1, Decision{Description: "Never"}),
+	}}
+	fallback := Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}}
+	offered := offeredDecisions(t, scenario, GameConfig{FallbackDecision: &fallback}, 2)
+	for turn, got := range offered {
+		if fmt.Sprint(got) != "[Pass turn]" {
+			t.Errorf("turn %v: got %v, want the fallback decision", turn, got)
+		}
+	}
+}
+
+// offeredDecisions plays turns of a game loop choosing the first choice each
+// time, returning the descriptions of the decisions offered.
+func offeredDecisions(t *testing.T, scenario Scenario, cfg GameConfig, turns int) [][]string {
+	t.Helper()
+	choiceCh := make(chan Choice)
+	decisionCh, worldCh, _, _, err := gameLoop(scenario, cfg, choiceCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offered [][]string
+	for turn := 0; turn < turns; turn++ {
+		<-worldCh
+		decisions := <-decisionCh
+		offered = append(offered, descriptions(decisions))
+		choiceCh <- decisions[0].Choices[0]
+	}
+	<-worldCh
+	<-decisionCh
+	close(choiceCh)
+	return offered
+}
+
+func TestGameLoopSeed(t *testing.T) {
+	var scenario Scenario
+	for i := 0; i < 5; i++ {
+		decision := Decision{Description: fmt.Sprintf("Decision %d", i), Choices: []Choice{{Description: "Accept"}}}
+		scenario.Rules = append(scenario.Rules, mustRule(t, decision.Description, "true", 0.5, decision))
+	}
+	fallback := Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}}
+	play := func(seed int64) string {
+		return fmt.Sprint(offeredDecisions(t, scenario, GameConfig{FallbackDecision: &fallback, Seed: seed}, 20))
+	}
+	if play(1) != play(1) {
+		t.Errorf("got different decisions with the same seed")
+	}
+	if play(1) == play(2) {
+		t.Errorf("got the same decisions with different seeds")
+	}
+}
+
+func TestApplyBounds(t *testing.T) {
+	bounds := map[string][2]int{"Money": {0, 5000}, "Military": {0, 100}}
+	tests := []struct {
+		name  string
+		key   string
+		power bool
+		value int
+		delta Delta
+		want  int
+	}{
+		{"below floor", "Money", false, 100, Delta{1, -500}, 0},
+		{"within bounds", "Money", false, 100, Delta{1, 50}, 150},
+		{"above ceiling", "Military", true, 90, Delta{2, 0}, 100},
+		{"unbounded", "Legislation", true, 10, Delta{1, -50}, -40},
+	}
+	for _, test := range tests {
+		world := World{Resources: map[string]int{}, Powers: map[string]int{}}.WithBounds(bounds)
+		change := Change{}
+		if test.power {
+			world.Powers[test.key] = test.value
+			change.Powers = map[string]Delta{test.key: test.delta}
+		} else {
+			world.Resources[test.key] = test.value
+			change.Resources = map[string]Delta{test.key: test.delta}
+		}
+		if err := world.Apply(Choice{Change: change}, nil); err != nil {
+			t.Fatal(err)
+		}
+		got := world.Resources[test.key]
+		if test.power {
+			got = world.Powers[test.key]
+		}
+		if got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestApplyNonNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   map[string][2]int
+		military int
+		money    int
+	}{
+		{"non-negative only", nil, 0, -500},
+		{"bounds allowing negatives", map[string][2]int{"Military": {-50, 100}}, 0, -500},
+		{"bounds above zero", map[string][2]int{"Military": {10, 100}}, 10, -500},
+	}
+	for _, test := range tests {
+		world := World{
+			Resources:   map[string]int{"Money": 500},
+			Powers:      map[string]int{"Military": 20},
+			NonNegative: map[string]bool{"Military": true},
+		}.WithBounds(test.bounds)
+		change := Change{
+			Resources: map[string]Delta{"Money": {1, -1000}},
+			Powers:    map[string]Delta{"Military": {1, -40}},
+		}
+		if err := world.Apply(Choice{Change: change}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if world.Powers["Military"] != test.military || world.Resources["Money"] != test.money {
+			t.Errorf("%v: got Military %v and Money %v, want %v and %v",
+				test.name, world.Powers["Military"], world.Resources["Money"], test.military, test.money)
+		}
+	}
+}
+
+func TestApplyReals(t *testing.T) {
+	world := World{
+		Resources:   map[string]int{"Money": 7},
+		Powers:      map[string]int{},
+		Reals:       map[string]float64{"Approval": 62.5},
+		Bounds:      map[string][2]int{"Approval": {0, 100}},
+		NonNegative: map[string]bool{},
+	}
+	halve := Choice{Change: Change{Resources: map[string]Delta{"Money": {0.5, 0}, "Approval": {0.5, 0}}}}
+	tests := []struct {
+		choice   Choice
+		money    int
+		approval float64
+	}{
+		{halve, 4, 31.25},
+		{halve, 2, 15.625},
+		{halve, 1, 7.8125},
+		{Choice{Change: Change{Resources: map[string]Delta{"Approval": {1, 500}}}}, 1, 100},
+	}
+	for i, test := range tests {
+		if err := world.Apply(test.choice, nil); err != nil {
+			t.Fatal(err)
+		}
+		if world.Resources["Money"] != test.money || world.Reals["Approval"] != test.approval {
+			t.Errorf("apply %v: got Money %v and Approval %v, want %v and %v",
+				i, world.Resources["Money"], world.Reals["Approval"], test.money, test.approval)
+		}
+	}
+	if _, ok := world.Resources["Approval"]; ok {
+		t.Errorf("got a fractional resource changed as an int")
+	}
+	copy := world.Copy()
+	copy.Reals["Approval"] = 0
+	if world.Reals["Approval"] != 100 {
+		t.Errorf("got Reals shared with a copy")
+	}
+}
+
+func TestApplyAll(t *testing.T) {
+	add := func(description string, money int) Choice {
+		return Choice{Description: description, Change: Change{Resources: map[string]Delta{"Money": {1, float64(money)}}}}
+	}
+	tests := []struct {
+		name    string
+		strict  bool
+		choices []Choice
+		money   int
+		err     string
+	}{
+		{"all within bounds", true, []Choice{add("Tax", 100), add("Spend", -200), add("Tax", 100)}, 500, ""},
+		{"second out of bounds", true, []Choice{add("Tax", 100), add("Spend", -1000), add("Tax", 100)}, 500, "choice 1 (Spend): Money would be -400, below its lower bound 0"},
+		{"clamped", false, []Choice{add("Tax", 100), add("Spend", -1000), add("Tax", 100)}, 100, ""},
+	}
+	for _, test := range tests {
+		world := World{Resources: map[string]int{"Money": 500}, Powers: map[string]int{}, StrictBounds: test.strict}.
+			WithBounds(map[string][2]int{"Money": {0, 1000}})
+		err := world.ApplyAll(test.choices, nil)
+		if test.err == "" && err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+		if got := world.Resources["Money"]; got != test.money {
+			t.Errorf("%v: got Money %v, want %v", test.name, got, test.money)
+		}
+	}
+}
+
+func TestDeltaOps(t *testing.T) {
+	tests := []struct {
+		delta Delta
+		want  int
+	}{
+		{Delta{2, 10}, 210},
+		{Delta{2, 10, float64(OpLinear)}, 210},
+		{Delta{42, 0, float64(OpSet)}, 42},
+		{Delta{-30, 0, float64(OpAdd)}, 70},
+		{Delta{1.5, 0, float64(OpMul)}, 150},
+		{Delta{10, 5, float64(OpAddPercent)}, 115},
+		{Delta{-25, 0, float64(OpAddPercent)}, 75},
+	}
+	for _, test := range tests {
+		if err := test.delta.check(); err != nil {
+			t.Errorf("%v: %v", test.delta, err)
+		}
+		if got := updatedValue(100, test.delta, nil, Round); got != test.want {
+			t.Errorf("%v applied to 100: got %v, want %v", test.delta, got, test.want)
+		}
+	}
+}
+
+func TestDeltaCheck(t *testing.T) {
+	tests := []Delta{
+		{1},
+		{1, 2, 3, 4},
+		{1, 2, -1},
+		{1, 2, 1.5},
+		{1, 2, float64(OpAddRandom) + 1},
+	}
+	for _, delta := range tests {
+		if err := delta.check(); err == nil {
+			t.Errorf("%v: got no error", delta)
+		}
+	}
+}
+
+func TestRandomDelta(t *testing.T) {
+	tests := []struct {
+		r    float64
+		want int
+	}{
+		{0, 500},
+		{0.5, 650},
+		{0.99, 797},
+	}
+	delta := Delta{-500, -200, float64(OpAddRandom)}
+	for _, test := range tests {
+		world := World{Resources: map[string]int{"Money": 1000}}
+		choice := Choice{Change: Change{Resources: map[string]Delta{"Money": delta}}}
+		if err := world.Apply(choice, fixedRand(test.r)); err != nil {
+			t.Fatal(err)
+		}
+		if got := world.Resources["Money"]; got != test.want {
+			t.Errorf("random %v: got %v, want %v", test.r, got, test.want)
+		}
+	}
+
+	world := World{Resources: map[string]int{"Money": 1000}}
+	choice := Choice{Change: Change{Resources: map[string]Delta{"Money": delta}}}
+	if err := world.Apply(choice, nil); err == nil {
+		t.Errorf("got no error applying a random delta without Rand")
+	}
+}
+
+func TestGameLoopConditions(t *testing.T) {
+	spend := Decision{Description: "Spend", Choices: []Choice{{
+		Description: "Accept",
+		Change:      Change{Resources: map[string]Delta{"Money": {-5000, 0, float64(OpAdd)}}},
+	}}}
+	scenario := Scenario{Rules: []Rule{mustRule(t, "spend", "true", 1, spend)}}
+	tests := []struct {
+		name string
+		cfg  GameConfig
+		want GameResult
+	}{
+		{
+			name: "lose",
+			cfg:  GameConfig{LoseConditions: []string{"World.Resources.Money <= 0"}},
+			want: GameResult{Outcome: Lose, Condition: "World.Resources.Money <= 0"},
+		},
+		{
+			name: "win first",
+			cfg: GameConfig{
+				WinConditions:  []string{"World.Powers.Military >= 90"},
+				LoseConditions: []string{"World.Resources.Money <= 0"},
+			},
+			want: GameResult{Outcome: Win, Condition: "World.Powers.Military >= 90"},
+		},
+	}
+	for _, test := range tests {
+		test.cfg.Seed = 1
+		choiceCh := make(chan Choice)
+		decisionCh, worldCh, resultCh, _, err := gameLoop(scenario, test.cfg, choiceCh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-worldCh
+		choiceCh <- (<-decisionCh)[0].Choices[0]
+		world := <-worldCh
+		if got := world.Resources["Money"]; got != -1000 {
+			t.Errorf("%v: got Money %v, want -1000", test.name, got)
+		}
+		if got, ok := <-resultCh; !ok || got != test.want {
+			t.Errorf("%v: got result %+v, want %+v", test.name, got, test.want)
+		}
+		if _, ok := <-decisionCh; ok {
+			t.Errorf("%v: got decisions after the game ended", test.name)
+		}
+	}
+
+	_, _, _, _, err := gameLoop(scenario, GameConfig{WinConditions: []string{"World.Resources.Money >"}}, nil)
+	if err == nil {
+		t.Errorf("got no error for an invalid condition")
+	}
+}
+
+func TestGameLoopError(t *testing.T) {
+	opts := GuardOptions{Funcs: Funcs{"broken": func(world World) bool {
+		if world.Turn > 0 {
+			panic("broken")
+		}
+		return false
+	}}}
+	broken, err := NewRuleWithOptions("broken", "broken(World)", 1, Decision{Description: "Broken"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := taxScenario(t)
+	scenario.Rules = append(scenario.Rules, broken)
+
+	choiceCh := make(chan Choice)
+	decisionCh, worldCh, _, errCh, err := gameLoop(scenario, GameConfig{Seed: 1}, choiceCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-worldCh
+	choiceCh <- (<-decisionCh)[0].Choices[0]
+	if err, ok := <-errCh; !ok || err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("got error %v, want the guard error", err)
+	}
+	if _, ok := <-decisionCh; ok {
+		t.Errorf("got decisions after an error")
+	}
+
+	e, err := NewEngine(scenario, GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Choose(e.Decisions()[0].Choices[0]); err == nil {
+		t.Errorf("got no error from Choose for a failing guard")
+	}
+}
+
+func TestTurnGatedRule(t *testing.T) {
+	election := Decision{Description: "Election", Choices: []Choice{{Description: "Accept"}}}
+	fallback := Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}}
+	scenario := Scenario{Rules: []Rule{mustRule(t, "election", "World.Turn >= 3", 1, election)}}
+	offered := offeredDecisions(t, scenario, GameConfig{FallbackDecision: &fallback, Seed: 1}, 5)
+	for turn, got := range offered {
+		want := "[Pass turn]"
+		if turn >= 3 {
+			want = "[Election]"
+		}
+		if fmt.Sprint(got) != want {
+			t.Errorf("turn %v: got %v, want %v", turn, got, want)
+		}
+	}
+
+	world := World{Turn: 7}
+	if got := world.Copy().Turn; got != 7 {
+		t.Errorf("got turn %v after Copy, want 7", got)
+	}
+}
+
+func TestRuleCooldown(t *testing.T) {
+	putsch := Decision{Description: "Putsch", Choices: []Choice{{Description: "Accept"}}}
+	rule := mustRule(t, "putsch", "true", 1, putsch)
+	rule.Cooldown = 3
+	scenario := Scenario{Rules: []Rule{rule}}
+	state := NewRuleState()
+	decisions := scenario.Decisions(fixedRand(0), state)
+
+	offered, err := decisions(World{Turn: 1}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offered) != 1 {
+		t.Fatalf("got %v decisions on turn 1, want 1", len(offered))
+	}
+	state.Fired(offered[0].Choices[0], 1)
+
+	for turn, want := range map[int]int{2: 0, 3: 0, 4: 1, 5: 1} {
+		offered, err := decisions(World{Turn: turn}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(offered) != want {
+			t.Errorf("turn %v: got %v decisions, want %v", turn, len(offered), want)
+		}
+	}
+}
+
+func TestOnceRule(t *testing.T) {
+	coup := Decision{Description: "Coup", Choices: []Choice{{Description: "Accept"}}}
+	fallback := Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}}
+	rule := mustRule(t, "coup", "true", 1, coup)
+	rule.Once = true
+	scenario := Scenario{Rules: []Rule{rule}}
+	offered := offeredDecisions(t, scenario, GameConfig{FallbackDecision: &fallback, Seed: 1}, 5)
+	for turn, got := range offered {
+		want := "[Pass turn]"
+		if turn == 0 {
+			want = "[Coup]"
+		}
+		if fmt.Sprint(got) != want {
+			t.Errorf("turn %v: got %v, want %v", turn, got, want)
+		}
+	}
+}
+
+func TestDecisionsRuleName(t *testing.T) {
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "putsch", "true", 1, Decision{Description: "Make putsch", Choices: []Choice{{Description: "Accept"}}}),
+	}}
+	decisions, err := scenario.Decisions(fixedRand(0), NewRuleState())(World{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decisions) != 1 || decisions[0].Rule != "putsch" {
+		t.Errorf("got %+v, want a decision of rule putsch", decisions)
+	}
+}
+
+func TestGuardFuncs(t *testing.T) {
+	funcs := Funcs{
+		"percent": func(x float64) float64 { return x / 100 },
+		"hasResource": func(world World, name string) bool {
+			_, ok := world.Resources[name]
+			return ok
+		},
+	}
+	tests := []struct {
+		guard string
+		money int
+		want  bool
+	}{
+		{"World.Resources.Money * percent(10) > 300", 4000, true},
+		{"World.Resources.Money * percent(10) > 300", 2000, false},
+		{`hasResource(World, "Money")`, 0, true},
+		{`hasResource(World, "Gold")`, 0, false},
+	}
+	for _, test := range tests {
+		rule, err := NewRuleWithOptions("rule", test.guard, 1, Decision{}, GuardOptions{Funcs: funcs})
+		if err != nil {
+			t.Fatalf("%v: %v", test.guard, err)
+		}
+		pass, err := rule.Pass(World{Resources: map[string]int{"Money": test.money}})
+		if err != nil || pass != test.want {
+			t.Errorf("%v with Money %v: got %v, %v, want %v", test.guard, test.money, pass, err, test.want)
+		}
+	}
+
+	if _, err := NewRule("rule", "percent(10) > 1", 1, Decision{}); err == nil {
+		t.Errorf("got no error calling an unregistered function")
+	}
+}
+
+func TestFlatNames(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 2000, "Foreign Aid": 5, "World": 1, "Shared": 1},
+		Powers:    map[string]int{"Military": 90, "Shared": 2},
+	}
+	tests := []struct {
+		guard string
+		want  bool
+	}{
+		{"Money > 1000 and Military >= 90", true},
+		{"Money > 1000 and Military > 90", false},
+		{"World.Resources.Money == Money", true},
+		{"Shared == 1", true},
+		{"double(Military) == 180", true},
+	}
+	opts := GuardOptions{FlatNames: true, Funcs: Funcs{"double": func(x int) int { return 2 * x }}}
+	for _, test := range tests {
+		guard, err := NewGuardWithOptions(test.guard, opts)
+		if err != nil {
+			t.Fatalf("%v: %v", test.guard, err)
+		}
+		pass, err := guard.Pass(world)
+		if err != nil || pass != test.want {
+			t.Errorf("%v: got %v, %v, want %v", test.guard, pass, err, test.want)
+		}
+	}
+
+	env := newGuardEnv()
+	env.vars["Stale"] = 1
+	env.fill(Guard{GuardOptions: opts}, world)
+	if _, ok := env.vars["Stale"]; ok {
+		t.Errorf("got a variable left over from a previous evaluation")
+	}
+	if _, ok := env.vars["Foreign Aid"]; ok {
+		t.Errorf("got a variable for a key that isn't an identifier")
+	}
+	if _, ok := env.vars["World"].(World); !ok {
+		t.Errorf("got World %T, want the world", env.vars["World"])
+	}
+}
+
+func TestGuardEnvReuse(t *testing.T) {
+	flat, err := NewGuardWithOptions("Money > 1000", GuardOptions{FlatNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := NewGuard(`res("Money", 0) > 1000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		money int
+		want  bool
+	}{
+		{2000, true},
+		{500, false},
+		{1001, true},
+		{1000, false},
+	}
+	for _, test := range tests {
+		for _, guard := range []Guard{flat, builtin} {
+			pass, err := guard.Pass(World{Resources: map[string]int{"Money": test.money}})
+			if err != nil || pass != test.want {
+				t.Errorf("%v with Money %v: got %v, %v, want %v", guard.Source, test.money, pass, err, test.want)
+			}
+		}
+	}
+}
+
+func BenchmarkGuardPass(b *testing.B) {
+	world := World{
+		Resources: map[string]int{"Money": 2000, "Popularity": 40},
+		Powers:    map[string]int{"Military": 90, "Legislation": 10},
+	}
+	tests := []struct {
+		name  string
+		guard string
+		opts  GuardOptions
+	}{
+		{"nested", "World.Resources.Money > 1000 and World.Powers.Military >= 90", GuardOptions{}},
+		{"flat", "Money > 1000 and Military >= 90", GuardOptions{FlatNames: true}},
+		{"builtins", `res("Money", 0) > 1000 and pow("Military", 0) >= 90`, GuardOptions{}},
+	}
+	for _, test := range tests {
+		b.Run(test.name, func(b *testing.B) {
+			guard, err := NewGuardWithOptions(test.guard, test.opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := guard.Pass(world); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestIsIdentifier(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"Money", true},
+		{"_x1", true},
+		{"", false},
+		{"1x", false},
+		{"Foreign Aid", false},
+		{"a-b", false},
+		{"and", false},
+		{"true", false},
+	}
+	for _, test := range tests {
+		if got := isIdentifier(test.s); got != test.want {
+			t.Errorf("%q: got %v, want %v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestMandatoryRules(t *testing.T) {
+	rule := func(name string, guard string, weight float64, mandatory bool) Rule {
+		r := mustRule(t, name, guard, weight, Decision{Description: name, Choices: []Choice{{Description: "Accept"}}})
+		r.Mandatory = mandatory
+		return r
+	}
+	scenario := Scenario{Rules: []Rule{
+		rule("optional high", "true", 0.9, false),
+		rule("mandatory low", "true", 0.1, true),
+		rule("mandatory failing", "false", 1, true),
+		rule("optional low", "true", 0.3, false),
+		rule("mandatory high", "true", 0.5, true),
+	}}
+	tests := []struct {
+		max  int
+		r    float64
+		want []string
+	}{
+		{1, 0, []string{"mandatory high"}},
+		{2, 0.99, []string{"mandatory high", "mandatory low"}},
+		{4, 0, []string{"mandatory high", "mandatory low", "optional high", "optional low"}},
+		// Optional rules only fill the remaining slots if the draw allows.
+		{4, 0.5, []string{"mandatory high", "mandatory low", "optional high"}},
+	}
+	for _, test := range tests {
+		decisions, err := scenario.Decisions(fixedRand(test.r), NewRuleState())(World{}, test.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("max %v, random %v: got %v, want %v", test.max, test.r, got, test.want)
+		}
+	}
+}
+
+func TestSafeRand(t *testing.T) {
+	const goroutines, draws = 20, 500
+	r := NewSafeRand(1)
+	got := make([][]float64, goroutines)
+	wait := sync.WaitGroup{}
+	for i := range got {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < draws; j++ {
+				got[i] = append(got[i], r.Float64())
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	var all []float64
+	for _, values := range got {
+		all = append(all, values...)
+	}
+	want := make([]float64, goroutines*draws)
+	seq := rand.New(rand.NewSource(1))
+	for i := range want {
+		want[i] = seq.Float64()
+	}
+	sort.Float64s(all)
+	sort.Float64s(want)
+	if fmt.Sprint(all) != fmt.Sprint(want) {
+		t.Errorf("got different numbers drawn concurrently than sequentially")
+	}
+}
+
+func TestChoiceGuards(t *testing.T) {
+	guard, err := NewGuard("World.Resources.Money >= 1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bribe := Decision{Description: "Bribe", Choices: []Choice{
+		{Description: "Accept", Guard: &guard},
+		{Description: "Reject"},
+	}}
+	lavish := Decision{Description: "Lavish", Choices: []Choice{
+		{Description: "Accept", Guard: &guard},
+	}}
+	scenario := Scenario{Rules: []Rule{
+		mustRule(t, "bribe", "true", 1, bribe),
+		mustRule(t, "lavish", "true", 1, lavish),
+	}}
+	tests := []struct {
+		money int
+		want  []string
+	}{
+		{999, []string{"Bribe: [Reject]"}},
+		{1000, []string{"Bribe: [Accept Reject]", "Lavish: [Accept]"}},
+		{500, []string{"Bribe: [Reject]"}},
+		{5000, []string{"Bribe: [Accept Reject]", "Lavish: [Accept]"}},
+	}
+	for _, test := range tests {
+		decisions, err := scenario.Decisions(fixedRand(0), NewRuleState())(World{Resources: map[string]int{"Money": test.money}}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, decision := range decisions {
+			choices := make([]string, len(decision.Choices))
+			for i, choice := range decision.Choices {
+				choices[i] = choice.Description
+			}
+			got = append(got, fmt.Sprintf("%v: %v", decision.Description, choices))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("Money %v: got %v, want %v", test.money, got, test.want)
+		}
+	}
+}
+
+func TestChoiceBranches(t *testing.T) {
+	costly, err := NewGuard("World.Powers.Legislation < 20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	free, err := NewGuard("World.Powers.Legislation >= 90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bribe := Choice{
+		Description: "Bribe",
+		Change:      Change{Resources: map[string]Delta{"Money": {1, -100}}},
+		Branches: []Branch{
+			{Guard: costly, Change: Change{Resources: map[string]Delta{"Money": {1, -200}}}},
+			{Guard: free, Change: Change{Powers: map[string]Delta{"Legislation": {1, -10}}}},
+		},
+	}
+	tests := []struct {
+		legislation int
+		money       int
+		after       int
+	}{
+		{10, 800, 10},
+		{19, 800, 19},
+		{20, 900, 20},
+		{50, 900, 50},
+		{95, 1000, 85},
+	}
+	for _, test := range tests {
+		world := World{Resources: map[string]int{"Money": 1000}, Powers: map[string]int{"Legislation": test.legislation}}
+		if err := world.Apply(bribe, nil); err != nil {
+			t.Fatal(err)
+		}
+		if world.Resources["Money"] != test.money || world.Powers["Legislation"] != test.after {
+			t.Errorf("Legislation %v: got %v, want Money %v and Legislation %v", test.legislation, world, test.money, test.after)
+		}
+	}
+}
+
+func TestPercentDeltas(t *testing.T) {
+	tests := []struct {
+		name  string
+		delta Delta
+		old   int
+		want  int
+	}{
+		{"percent increase", PercentChange(10), 100, 110},
+		{"percent decrease", PercentChange(-10), 100, 90},
+		{"no percent change", PercentChange(0), 100, 100},
+		{"scale down", ScaleTo(0.1), 100, 10},
+		{"scale up", ScaleTo(2), 100, 200},
+	}
+	for _, test := range tests {
+		if got := updatedValue(test.old, test.delta, fixedRand(0), Round); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNonBoolGuards(t *testing.T) {
+	tests := []struct {
+		guard string
+		opts  GuardOptions
+		err   string
+	}{
+		{"World.Resources.Money", GuardOptions{}, `guard "World.Resources.Money" does not evaluate to bool`},
+		{`"yes"`, GuardOptions{}, `guard "\"yes\"" does not evaluate to bool`},
+		{"42", GuardOptions{}, `guard "42" does not evaluate to bool`},
+		{"World.Resources.Money > 0", GuardOptions{}, ""},
+		// Results of custom functions are only checked when evaluated.
+		{"isRich(World)", GuardOptions{Funcs: Funcs{"isRich": func(World) bool { return true }}}, ""},
+	}
+	for _, test := range tests {
+		_, err := NewGuardWithOptions(test.guard, test.opts)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%v: got error %v", test.guard, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%v: got error %v, want %v", test.guard, err, test.err)
+		}
+	}
+
+	// Flat names can't be type checked, so the guard fails when evaluated.
+	guard, err := NewGuardWithOptions("Money", GuardOptions{FlatNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: "tax", Guard: guard, Weight: 1}
+	_, err = rule.Evaluate(World{Resources: map[string]int{"Money": 100}})
+	if want := `rule tax: guard "Money" did not evaluate to bool, got int`; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %v", err, want)
+	}
+}
+
+func TestDecisionsStableOrder(t *testing.T) {
+	var scenario Scenario
+	var heavy, light []string
+	for i := 0; i < 50; i++ {
+		// Unnamed rules of the same weight tie, and alternating weights make
+		// the sort move them around.
+		description := fmt.Sprintf("Decision %d", i)
+		weight := 0.5
+		if i%2 == 0 {
+			weight = 0.6
+			heavy = append(heavy, description)
+		} else {
+			light = append(light, description)
+		}
+		scenario.Rules = append(scenario.Rules, mustRule(t, "", "true", weight, Decision{Description: description, Choices: []Choice{{Description: "Accept"}}}))
+	}
+	want := append(heavy, light...)
+	for _, mode := range []SelectionMode{Independent, TopN} {
+		scenario.Mode = mode
+		for run := 0; run < 10; run++ {
+			decisions, err := scenario.Decisions(fixedRand(0.25), NewRuleState())(World{}, 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("%v, run %v: got %v, want %v", mode, run, got, want)
+			}
+		}
+	}
+}
+
+func TestNegativeWeights(t *testing.T) {
+	rule := func(name, guard string, weight float64, group string) Rule {
+		r := mustRule(t, name, guard, weight, Decision{Description: name, Choices: []Choice{{Description: "Accept"}}})
+		r.Group = group
+		return r
+	}
+	mandatory := rule("veto", "true", -0.5, "")
+	mandatory.Mandatory = true
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  []string
+	}{
+		{"never offered", []Rule{rule("veto", "true", -0.5, ""), rule("tax", "true", 0.5, "")}, []string{"tax"}},
+		{"mandatory", []Rule{mandatory, rule("tax", "true", 0.5, "")}, []string{"tax"}},
+		{"suppressing", []Rule{rule("war", "true", 0.8, "foreign"), rule("peace", "true", -0.5, "foreign"), rule("tax", "true", 0.5, "")}, []string{"tax", "war"}},
+		{"guard failing", []Rule{rule("war", "true", 0.8, "foreign"), rule("peace", "false", -0.5, "foreign"), rule("tax", "true", 0.5, "")}, []string{"war", "tax"}},
+		{"other group", []Rule{rule("war", "true", 0.8, "foreign"), rule("strike", "true", -0.5, "domestic"), rule("tax", "true", 0.5, "")}, []string{"war", "tax"}},
+		{"suppressed entirely", []Rule{rule("war", "true", 0.8, "foreign"), rule("peace", "true", -1, "foreign"), rule("tax", "true", 0.5, "")}, []string{"tax"}},
+	}
+	for _, test := range tests {
+		scenario := Scenario{Rules: test.rules, Mode: TopN}
+		decisions, err := scenario.Decisions(fixedRand(0), NewRuleState())(World{}, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRuleGroups(t *testing.T) {
+	var scenario Scenario
+	for _, rule := range []struct {
+		name   string
+		weight float64
+		group  string
+	}{
+		{"Snap election", 0.9, "Election"},
+		{"Regular election", 0.7, "Election"},
+		{"Rigged election", 0.5, "Election"},
+		{"Tax", 0.6, ""},
+		{"Harvest", 0.4, ""},
+	} {
+		r := mustRule(t, "", "true", rule.weight, Decision{Description: rule.name, Choices: []Choice{{Description: "Accept"}}})
+		r.Group = rule.group
+		scenario.Rules = append(scenario.Rules, r)
+	}
+	decide := scenario.Decisions(rand.New(rand.NewSource(1)), NewRuleState())
+	counts := make(map[string]int)
+	for turn := 0; turn < 200; turn++ {
+		decisions, err := decide(World{}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elections := 0
+		for _, decision := range decisions {
+			counts[decision.Description]++
+			if strings.HasSuffix(decision.Description, "election") {
+				elections++
+			}
+		}
+		if elections > 1 {
+			t.Fatalf("turn %v: got %v elections offered in %v", turn, elections, descriptions(decisions))
+		}
+	}
+	for _, name := range []string{"Snap election", "Tax", "Harvest"} {
+		if counts[name] == 0 {
+			t.Errorf("got %v never offered", name)
+		}
+	}
+}
+
+func TestRuleGroupsTopN(t *testing.T) {
+	rule := func(name string, weight float64, group string, mandatory bool) Rule {
+		r := mustRule(t, name, "true", weight, Decision{Description: name, Choices: []Choice{{Description: "Accept"}}})
+		r.Group = group
+		r.Mandatory = mandatory
+		return r
+	}
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  []string
+	}{
+		{
+			name:  "loser ranked before others",
+			rules: []Rule{rule("A", 0.9, "g", false), rule("B", 0.8, "g", false), rule("C", 0.7, "", false)},
+			want:  []string{"A", "C"},
+		},
+		{
+			name:  "mandatory",
+			rules: []Rule{rule("A", 0.9, "g", true), rule("B", 0.8, "g", true), rule("C", 0.7, "", false)},
+			want:  []string{"A", "C"},
+		},
+		{
+			name:  "mandatory ahead of optional",
+			rules: []Rule{rule("A", 0.9, "g", false), rule("B", 0.2, "g", true), rule("C", 0.7, "", false)},
+			want:  []string{"B", "C"},
+		},
+		{
+			name:  "other groups",
+			rules: []Rule{rule("A", 0.9, "g", true), rule("B", 0.8, "h", true), rule("C", 0.7, "h", false)},
+			want:  []string{"A", "B"},
+		},
+	}
+	for _, test := range tests {
+		scenario := Scenario{Rules: test.rules, Mode: TopN}
+		decisions, err := scenario.Decisions(fixedRand(0), NewRuleState())(World{}, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(decisions); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
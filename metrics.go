@@ -0,0 +1,91 @@
+//go:build prometheus
+// +build prometheus
+
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exports operational metrics of the engines it instruments, e.g.
+// those of a game server, to Prometheus. It's only built with the
+// prometheus build tag so that the client is only a dependency when used.
+type Metrics struct {
+	activeEngines    prometheus.Gauge
+	turns            prometheus.Counter
+	decisionsOffered prometheus.Counter
+	choicesApplied   prometheus.Counter
+	gamesEnded       *prometheus.CounterVec
+}
+
+// NewMetrics creates the collectors and registers them with reg, e.g.
+// prometheus.DefaultRegisterer.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		activeEngines: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "politika_active_engines",
+			Help: "Number of games being played.",
+		}),
+		turns: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "politika_turns_total",
+			Help: "Number of turns played.",
+		}),
+		decisionsOffered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "politika_decisions_offered_total",
+			Help: "Number of decisions offered.",
+		}),
+		choicesApplied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "politika_choices_applied_total",
+			Help: "Number of choices applied.",
+		}),
+		gamesEnded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "politika_games_ended_total",
+			Help: "Number of games ended, by outcome.",
+		}, []string{"outcome"}),
+	}
+	for _, c := range []prometheus.Collector{m.activeEngines, m.turns, m.decisionsOffered, m.choicesApplied, m.gamesEnded} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Instrument counts the events of e, which is active until its game ends
+// or the returned function is called, e.g. when its session is closed.
+// Calling it unregisters the event handler, so it mustn't be called
+// concurrently with the methods of e.
+func (m *Metrics) Instrument(e *Engine) (done func()) {
+	m.activeEngines.Inc()
+	var once sync.Once
+	var unregister func()
+	done = func() {
+		once.Do(func() {
+			unregister()
+			m.activeEngines.Dec()
+		})
+	}
+	turn := e.Current().Turn
+	unregister = e.OnEvent(func(event Event) {
+		if event.Turn > turn {
+			m.turns.Add(float64(event.Turn - turn))
+			turn = event.Turn
+		}
+		switch event.Kind {
+		case DecisionsOffered:
+			m.decisionsOffered.Add(float64(len(event.Decisions)))
+		case ChoiceApplied:
+			m.choicesApplied.Inc()
+		case GameEnded:
+			outcome := "stuck"
+			if event.Result != nil {
+				outcome = event.Result.Outcome.String()
+			}
+			m.gamesEnded.WithLabelValues(outcome).Inc()
+			done()
+		}
+	})
+	return done
+}
@@ -0,0 +1,72 @@
+//go:build prometheus
+// +build prometheus
+
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := GameConfig{Seed: 1, WinConditions: []string{"World.Resources.Money >= 4200"}}
+	won, err := NewEngine(taxScenario(t), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Instrument(won)
+	closed, err := NewEngine(taxScenario(t), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := m.Instrument(closed)
+	mustChoose(t, closed, "Raise")
+	if got := testutil.ToFloat64(m.activeEngines); got != 2 {
+		t.Errorf("got %v active engines, want 2", got)
+	}
+	done()
+	done()
+	if len(closed.handlers) != 0 {
+		t.Errorf("got %v event handlers left after done, want 0", len(closed.handlers))
+	}
+	mustChoose(t, closed, "Lower")
+	mustChoose(t, won, "Raise")
+	mustChoose(t, won, "Raise")
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		want      float64
+	}{
+		{"active engines", m.activeEngines, 0},
+		{"turns", m.turns, 3},
+		{"decisions offered", m.decisionsOffered, 2},
+		{"choices applied", m.choicesApplied, 3},
+		{"games won", m.gamesEnded.WithLabelValues("win"), 1},
+		{"games lost", m.gamesEnded.WithLabelValues("lose"), 0},
+	}
+	for _, test := range tests {
+		if got := testutil.ToFloat64(test.collector); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+	if len(won.handlers) != 0 {
+		t.Errorf("got %v event handlers left after the game ended, want 0", len(won.handlers))
+	}
+}
+
+func TestMetricsRegisteredTwice(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := NewMetrics(reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMetrics(reg); err == nil {
+		t.Errorf("got no error registering the collectors twice")
+	}
+}
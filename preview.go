@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expectedRand makes random deltas take their expected value.
+type expectedRand struct{}
+
+func (expectedRand) Float64() float64 {
+	return 0.5
+}
+
+// ApplyPreview returns the world that applying choice to w would result in,
+// along with the signed change of each resource and power the choice
+// affects, without modifying w. Random deltas take their expected value.
+// Changes of fractional resources are rounded like values are.
+func (w World) ApplyPreview(choice Choice) (World, map[string]int, error) {
+	change, err := choice.change(w)
+	if err != nil {
+		return World{}, nil, err
+	}
+	next := w.Copy()
+	if err := next.Apply(choice, expectedRand{}); err != nil {
+		return World{}, nil, err
+	}
+	deltas := make(map[string]int)
+	for _, values := range []struct {
+		old, new map[string]int
+		changed  map[string]Delta
+	}{{w.Powers, next.Powers, change.Powers}, {w.Resources, next.Resources, change.Resources}} {
+		for key, value := range values.new {
+			_, changed := values.changed[key]
+			if changed || value != values.old[key] {
+				deltas[key] = value - values.old[key]
+			}
+		}
+	}
+	for key, value := range next.Reals {
+		_, changed := change.Resources[key]
+		if changed || value != w.Reals[key] {
+			deltas[key] = w.rounding(key).round(value - w.Reals[key])
+		}
+	}
+	return next, deltas, nil
+}
+
+// Preview returns the values the resources and powers affected by c would
+// have if c were applied to world, without modifying it, fractional ones
+// rounded. Random deltas are previewed at their expected value. Nothing is
+// previewed if a branch guard fails to evaluate.
+func (c Choice) Preview(world World) map[string]int {
+	next, deltas, err := world.ApplyPreview(c)
+	if err != nil {
+		return nil
+	}
+	preview := make(map[string]int, len(deltas))
+	for key := range deltas {
+		if value, ok := next.Powers[key]; ok {
+			preview[key] = value
+		}
+		if value, ok := next.Resources[key]; ok {
+			preview[key] = value
+		}
+		if value, ok := next.Reals[key]; ok {
+			preview[key] = next.rounding(key).round(value)
+		}
+	}
+	return preview
+}
+
+// formatPreview formats a preview as e.g. "Legislation→100, Money→2000".
+func formatPreview(preview map[string]int) string {
+	keys := sortedIntKeys(preview)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%v→%v", key, preview[key])
+	}
+	return strings.Join(parts, ", ")
+}
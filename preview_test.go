@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChoicePreview(t *testing.T) {
+	world := World{
+		Resources: map[string]int{"Money": 4000},
+		Powers:    map[string]int{"Military": 90, "Legislation": 10},
+		Reals:     map[string]float64{"Approval": 62.5},
+		Bounds:    map[string][2]int{"Military": {0, 100}},
+	}
+	tests := []struct {
+		name   string
+		change Change
+		want   map[string]int
+	}{
+		{
+			name: "multiplicative",
+			change: Change{
+				Resources: map[string]Delta{"Money": {0.5, 0}},
+				Powers:    map[string]Delta{"Military": {0.1, 0}},
+			},
+			want: map[string]int{"Money": 2000, "Military": 9},
+		},
+		{
+			name:   "additive",
+			change: Change{Powers: map[string]Delta{"Legislation": {0, 100}}},
+			want:   map[string]int{"Legislation": 100},
+		},
+		{
+			name: "mixed",
+			change: Change{
+				Resources: map[string]Delta{"Money": {1.5, -1000}},
+				Powers:    map[string]Delta{"Legislation": {5, 0, float64(OpAdd)}},
+			},
+			want: map[string]int{"Money": 5000, "Legislation": 15},
+		},
+		{
+			name:   "clamped",
+			change: Change{Powers: map[string]Delta{"Military": {2, 0}}},
+			want:   map[string]int{"Military": 100},
+		},
+		{
+			name:   "random",
+			change: Change{Resources: map[string]Delta{"Money": {-500, -200, float64(OpAddRandom)}}},
+			want:   map[string]int{"Money": 3650},
+		},
+		{
+			name:   "fractional",
+			change: Change{Resources: map[string]Delta{"Approval": {0.5, 0}}},
+			want:   map[string]int{"Approval": 31},
+		},
+	}
+	before := world.Copy()
+	for _, test := range tests {
+		if got := (Choice{Change: test.change}).Preview(world); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+	if !reflect.DeepEqual(world, before) {
+		t.Errorf("got %+v after previews, want %+v", world, before)
+	}
+}
+
+func TestFormatPreview(t *testing.T) {
+	tests := []struct {
+		preview map[string]int
+		want    string
+	}{
+		{nil, ""},
+		{map[string]int{"Money": 2000}, "Money→2000"},
+		{map[string]int{"Money": -5, "Legislation": 100}, "Legislation→100, Money→-5"},
+	}
+	for _, test := range tests {
+		if got := formatPreview(test.preview); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.preview, got, test.want)
+		}
+	}
+}
+
+func TestApplyPreview(t *testing.T) {
+	scenario, err := LoadScenario("scenarios/simple.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accept, reject := scenario.Rules[0].Choices[0], scenario.Rules[0].Choices[1]
+	tests := []struct {
+		name   string
+		choice Choice
+		next   World
+		deltas map[string]int
+	}{
+		{
+			name:   "accept",
+			choice: accept,
+			next: World{
+				Resources: map[string]int{"Money": 1000, "Popularity": 0},
+				Powers:    map[string]int{"Military": 90, "Legislation": 100},
+			},
+			deltas: map[string]int{"Money": -1000, "Popularity": -30, "Legislation": 90},
+		},
+		{
+			name:   "reject",
+			choice: reject,
+			next: World{
+				Resources: map[string]int{"Money": 2000, "Popularity": 30},
+				Powers:    map[string]int{"Military": 9, "Legislation": 10},
+			},
+			deltas: map[string]int{"Military": -81},
+		},
+		{
+			name: "fractional",
+			choice: Choice{Change: Change{Resources: map[string]Delta{
+				"Approval": {-12.75, 0, float64(OpAdd)},
+				"Trust":    {0, 0, float64(OpAdd)},
+			}}},
+			next: World{
+				Resources: map[string]int{"Money": 2000, "Popularity": 30},
+				Powers:    map[string]int{"Military": 90, "Legislation": 10},
+			},
+			deltas: map[string]int{"Approval": -13, "Trust": 0},
+		},
+	}
+	for _, test := range tests {
+		world := World{
+			Resources: map[string]int{"Money": 2000, "Popularity": 30},
+			Powers:    map[string]int{"Military": 90, "Legislation": 10},
+			Reals:     map[string]float64{"Approval": 62.5, "Trust": 1},
+		}
+		before := world.Copy()
+		next, deltas, err := world.ApplyPreview(test.choice)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(world, before) {
+			t.Errorf("%v: got %v after the preview, want it unchanged at %v", test.name, world, before)
+		}
+		if !reflect.DeepEqual(next.Resources, test.next.Resources) || !reflect.DeepEqual(next.Powers, test.next.Powers) {
+			t.Errorf("%v: got world %v, want %v", test.name, next, test.next)
+		}
+		if !reflect.DeepEqual(deltas, test.deltas) {
+			t.Errorf("%v: got deltas %v, want %v", test.name, deltas, test.deltas)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// protocolCommand is a command read by runProtocol, e.g. {"cmd":"state"} or
+// {"cmd":"choose","index":1}.
+type protocolCommand struct {
+	Cmd   string `json:"cmd"`
+	Index int    `json:"index"`
+}
+
+// protocolResponse is either the game state or an error.
+type protocolResponse struct {
+	*stateView
+	Error string `json:"error,omitempty"`
+}
+
+// runProtocol plays a game driven by newline-delimited JSON commands read
+// from in, writing one JSON response per command to out. It returns when in
+// is exhausted.
+func runProtocol(engine *Engine, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var resp protocolResponse
+		if err := handleCommand(engine, scanner.Bytes()); err != nil {
+			resp.Error = err.Error()
+		} else {
+			state := newStateView(engine)
+			resp.stateView = &state
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func handleCommand(engine *Engine, line []byte) error {
+	var cmd protocolCommand
+	if err := json.Unmarshal(line, &cmd); err != nil {
+		return fmt.Errorf("invalid command: %v", err)
+	}
+	switch cmd.Cmd {
+	case "state":
+		return nil
+	case "choose":
+		return chooseIndex(engine, cmd.Index)
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Cmd)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunProtocol(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := []string{
+		`{"cmd":"state"}`,
+		`{"cmd":"choose","index":0}`,
+		``,
+		`{"cmd":"choose","index":5}`,
+		`{"cmd":"resign"}`,
+		`garbage`,
+		`{"cmd":"choose","index":1}`,
+	}
+	tests := []struct {
+		err   string
+		money int
+		turn  int
+	}{
+		{"", 4000, 0},
+		{"", 4100, 1},
+		{"no choice 5", 0, 0},
+		{`unknown command "resign"`, 0, 0},
+		{"invalid command", 0, 0},
+		{"", 4000, 2},
+	}
+	var out bytes.Buffer
+	if err := runProtocol(e, strings.NewReader(strings.Join(commands, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	frames := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(frames) != len(tests) {
+		t.Fatalf("got %v frames, want %v:\n%v", len(frames), len(tests), out.String())
+	}
+	for i, test := range tests {
+		var frame struct {
+			World     *World         `json:"world"`
+			Decisions []decisionView `json:"decisions"`
+			Error     string         `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(frames[i]), &frame); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if test.err != "" {
+			if !strings.HasPrefix(frame.Error, test.err) || frame.World != nil {
+				t.Errorf("frame %d: got %v, want error %v", i, frames[i], test.err)
+			}
+			continue
+		}
+		if frame.Error != "" || frame.World == nil {
+			t.Errorf("frame %d: got %v, want the state", i, frames[i])
+			continue
+		}
+		if frame.World.Resources["Money"] != test.money || frame.World.Turn != test.turn {
+			t.Errorf("frame %d: got Money %v on turn %v, want %v on turn %v", i, frame.World.Resources["Money"], frame.World.Turn, test.money, test.turn)
+		}
+		if len(frame.Decisions) != 1 || frame.Decisions[0].Description != "Tax" {
+			t.Errorf("frame %d: got decisions %+v, want Tax", i, frame.Decisions)
+		}
+	}
+}
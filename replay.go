@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Replay plays a game with the given seed by making the recorded choices in
+// order, and returns the final world. The seed must be the one the game was
+// played with, as returned by Engine.Seed; zero, which would seed from the
+// clock, is an error.
+func Replay(scenario Scenario, seed int64, choices []Choice) (World, error) {
+	e, err := replay(scenario, seed, choices)
+	if err != nil {
+		return World{}, err
+	}
+	return e.Current(), nil
+}
+
+// replayAt replays the first step choices like Replay, returning the world
+// and the decisions offered then.
+func replayAt(scenario Scenario, seed int64, choices []Choice, step int) (World, []Decision, error) {
+	if step < 0 || step > len(choices) {
+		return World{}, nil, fmt.Errorf("no step %d", step)
+	}
+	e, err := replay(scenario, seed, choices[:step])
+	if err != nil {
+		return World{}, nil, err
+	}
+	return e.Current(), e.Decisions(), nil
+}
+
+func replay(scenario Scenario, seed int64, choices []Choice) (*Engine, error) {
+	if seed == 0 {
+		return nil, fmt.Errorf("no seed to replay with")
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: seed})
+	if err != nil {
+		return nil, err
+	}
+	for i, choice := range choices {
+		offered, ok := e.offered(choice)
+		if !ok {
+			return nil, fmt.Errorf("step %d: choice %q is not offered", i, choice.Description)
+		}
+		if err := e.Choose(offered); err != nil {
+			return nil, fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+	return e, nil
+}
+
+// offered returns the currently offered choice matching choice's
+// description and, if known, the rule it came from.
+func (e *Engine) offered(choice Choice) (Choice, bool) {
+	for _, decision := range e.decisions {
+		for _, c := range decision.Choices {
+			if c.Description == choice.Description && (choice.rule == 0 || c.rule == choice.rule) {
+				return c, true
+			}
+		}
+	}
+	return Choice{}, false
+}
+
+// FirstDivergence replays choices with the given seed on both the old and
+// the new version of a scenario and returns the first turn at which they
+// offer different decisions, or at which the choice made leaves their
+// resources, fractional or not, and powers different, along with a
+// description of the difference. If the playthroughs don't diverge, turn
+// is -1. The choices must be valid for old and, like with Replay, the seed
+// must not be zero.
+func FirstDivergence(old, new Scenario, seed int64, choices []Choice) (turn int, reason string, err error) {
+	if seed == 0 {
+		return 0, "", fmt.Errorf("no seed to replay with")
+	}
+	oldEngine, err := NewEngine(old, GameConfig{Seed: seed})
+	if err != nil {
+		return 0, "", fmt.Errorf("old scenario: %v", err)
+	}
+	newEngine, err := NewEngine(new, GameConfig{Seed: seed})
+	if err != nil {
+		return 0, "", fmt.Errorf("new scenario: %v", err)
+	}
+	for i, choice := range choices {
+		turn := oldEngine.world.Turn
+		oldOffered, newOffered := describeDecisions(oldEngine.decisions), describeDecisions(newEngine.decisions)
+		if oldOffered != newOffered {
+			return turn, fmt.Sprintf("offered %v instead of %v", newOffered, oldOffered), nil
+		}
+		offered, ok := oldEngine.offered(choice)
+		if !ok {
+			return 0, "", fmt.Errorf("step %d: choice %q is not offered", i, choice.Description)
+		}
+		if err := oldEngine.Choose(offered); err != nil {
+			return 0, "", fmt.Errorf("step %d: old scenario: %v", i, err)
+		}
+		// The decisions are the same, so the choice is offered by both.
+		offered, _ = newEngine.offered(choice)
+		if err := newEngine.Choose(offered); err != nil {
+			return turn, fmt.Sprintf("choice %q failed: %v", choice.Description, err), nil
+		}
+		if diff := Diff(oldEngine.world, newEngine.world); len(diff) > 0 {
+			return turn, fmt.Sprintf("choice %q changed the world by %v more", choice.Description, diff), nil
+		}
+		if diff := DiffReals(oldEngine.world, newEngine.world); len(diff) > 0 {
+			return turn, fmt.Sprintf("choice %q changed the world by %v more", choice.Description, diff), nil
+		}
+	}
+	return -1, "", nil
+}
+
+// describeDecisions lists decisions with their choices, e.g.
+// "[Raise taxes? (Yes, No)]".
+func describeDecisions(decisions []Decision) string {
+	descriptions := make([]string, len(decisions))
+	for i, decision := range decisions {
+		choices := make([]string, len(decision.Choices))
+		for j, choice := range decision.Choices {
+			choices[j] = choice.Description
+		}
+		descriptions[i] = fmt.Sprintf("%v (%v)", decision.Description, strings.Join(choices, ", "))
+	}
+	return "[" + strings.Join(descriptions, ", ") + "]"
+}
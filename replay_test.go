@@ -0,0 +1,153 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	scenario := randomScenario(t)
+	e, err := NewEngine(scenario, GameConfig{Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var choices []Choice
+	for turn := 0; turn < 6 && len(e.Decisions()) > 0; turn++ {
+		choice := e.Decisions()[0].Choices[0]
+		choices = append(choices, choice)
+		if err := e.Choose(choice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(choices) < 2 {
+		t.Fatalf("got only %v turns played", len(choices))
+	}
+
+	got, err := Replay(scenario, e.Seed(), choices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := e.Current(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		seed    int64
+		choices []Choice
+		err     string
+	}{
+		{"choice not offered", 1, []Choice{{Description: "Raise"}, {Description: "Abdicate"}}, `step 1: choice "Abdicate"`},
+		{"no seed", 0, []Choice{{Description: "Raise"}}, "no seed to replay with"},
+	}
+	for _, test := range tests {
+		_, err := Replay(taxScenario(t), test.seed, test.choices)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestFirstDivergence(t *testing.T) {
+	crisis := func(weight float64) Scenario {
+		scenario := taxScenario(t)
+		decision := Decision{Description: "Crisis", Choices: []Choice{{Description: "Endure"}}}
+		scenario.Rules = append(scenario.Rules, mustRule(t, "crisis", "World.Turn >= 2", weight, decision))
+		return scenario
+	}
+	richer := taxScenario(t)
+	richer.Rules[0].Choices[0].Change.Resources["Money"] = Delta{200, 0, float64(OpAdd)}
+	approving := func(approval float64) Scenario {
+		scenario := taxScenario(t)
+		scenario.InitialWorld = &World{Resources: map[string]int{"Money": 4000}, Reals: map[string]float64{"Approval": 50}}
+		scenario.Rules[0].Choices[0].Change.Resources["Approval"] = Delta{approval, 0, float64(OpAdd)}
+		return scenario
+	}
+	raise := []Choice{{Description: "Raise"}, {Description: "Raise"}, {Description: "Lower"}, {Description: "Raise"}}
+	tests := []struct {
+		name     string
+		old, new Scenario
+		seed     int64
+		choices  []Choice
+		turn     int
+		reason   string
+		err      string
+	}{
+		{name: "same", old: crisis(0), new: crisis(0), seed: 1, choices: raise, turn: -1},
+		{name: "no choices", old: crisis(0), new: crisis(1), seed: 1, choices: nil, turn: -1},
+		{
+			name: "weight", old: crisis(0), new: crisis(1), seed: 1, choices: raise, turn: 2,
+			reason: "offered [Crisis (Endure), Tax (Raise, Lower)] instead of [Tax (Raise, Lower)]",
+		},
+		{
+			name: "delta", old: taxScenario(t), new: richer, seed: 1, choices: raise, turn: 0,
+			reason: `choice "Raise" changed the world by map[Money:100] more`,
+		},
+		{
+			name: "fractional delta", old: approving(0.5), new: approving(0.75), seed: 1, choices: raise, turn: 0,
+			reason: `choice "Raise" changed the world by map[Approval:0.25] more`,
+		},
+		{
+			name: "invalid choice", old: taxScenario(t), new: taxScenario(t), seed: 1, choices: []Choice{{Description: "Abdicate"}},
+			err: `step 0: choice "Abdicate" is not offered`,
+		},
+		{
+			name: "no seed", old: taxScenario(t), new: richer, choices: raise,
+			err: "no seed to replay with",
+		},
+	}
+	for _, test := range tests {
+		turn, reason, err := FirstDivergence(test.old, test.new, test.seed, test.choices)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if turn != test.turn || reason != test.reason {
+			t.Errorf("%v: got turn %v, %q, want %v, %q", test.name, turn, reason, test.turn, test.reason)
+		}
+	}
+}
+
+func TestReplayAt(t *testing.T) {
+	choices := []Choice{{Description: "Raise"}, {Description: "Raise"}, {Description: "Lower"}, {Description: "Raise"}}
+	tests := []struct {
+		step  int
+		turn  int
+		money int
+		err   string
+	}{
+		{0, 0, 4000, ""},
+		{1, 1, 4100, ""},
+		{3, 3, 4100, ""},
+		{4, 4, 4200, ""},
+		{2, 2, 4200, ""},
+		{5, 0, 0, "no step 5"},
+		{-1, 0, 0, "no step -1"},
+	}
+	for _, test := range tests {
+		world, decisions, err := replayAt(taxScenario(t), 1, choices, test.step)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("step %v: got error %v, want %v", test.step, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("step %v: %v", test.step, err)
+		}
+		if world.Turn != test.turn || world.Resources["Money"] != test.money {
+			t.Errorf("step %v: got turn %v and Money %v, want %v and %v", test.step, world.Turn, world.Resources["Money"], test.turn, test.money)
+		}
+		if got := descriptions(decisions); len(got) != 1 || got[0] != "Tax" {
+			t.Errorf("step %v: got decisions %v, want [Tax]", test.step, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// RoundingMode selects how values changed by a Delta are rounded to ints.
+type RoundingMode int
+
+const (
+	// Round rounds to the nearest int, halves away from zero.
+	Round RoundingMode = iota
+	// Floor rounds down, e.g. to never undercharge costs.
+	Floor
+	// Ceil rounds up.
+	Ceil
+	// Truncate rounds towards zero.
+	Truncate
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case Round:
+		return "round"
+	case Floor:
+		return "floor"
+	case Ceil:
+		return "ceil"
+	case Truncate:
+		return "truncate"
+	default:
+		return fmt.Sprintf("RoundingMode(%d)", int(m))
+	}
+}
+
+func (m RoundingMode) round(x float64) int {
+	switch m {
+	case Floor:
+		return int(math.Floor(x))
+	case Ceil:
+		return int(math.Ceil(x))
+	case Truncate:
+		return int(math.Trunc(x))
+	default:
+		return int(math.Round(x))
+	}
+}
+
+// rounding returns the rounding mode of the resource or power key.
+func (w World) rounding(key string) RoundingMode {
+	if mode, ok := w.RoundingByKey[key]; ok {
+		return mode
+	}
+	return w.Rounding
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestRoundingMode(t *testing.T) {
+	tests := []struct {
+		mode RoundingMode
+		x    float64
+		want int
+	}{
+		{Round, 3.5, 4},
+		{Round, -3.5, -4},
+		{Round, 3.2, 3},
+		{Floor, 3.5, 3},
+		{Floor, -3.2, -4},
+		{Ceil, 3.2, 4},
+		{Ceil, -3.5, -3},
+		{Truncate, 3.7, 3},
+		{Truncate, -3.7, -3},
+		{Floor, 3, 3},
+	}
+	for _, test := range tests {
+		if got := test.mode.round(test.x); got != test.want {
+			t.Errorf("%v %v: got %v, want %v", test.mode, test.x, got, test.want)
+		}
+	}
+}
+
+func TestApplyRounding(t *testing.T) {
+	halve := Choice{Change: Change{
+		Resources: map[string]Delta{"Money": {0.5, 0}},
+		Powers:    map[string]Delta{"Military": {-0.5, 0}},
+	}}
+	tests := []struct {
+		rounding RoundingMode
+		byKey    map[string]RoundingMode
+		money    int
+		military int
+	}{
+		{Round, nil, 4, -4},
+		{Floor, nil, 3, -4},
+		{Ceil, nil, 4, -3},
+		{Truncate, nil, 3, -3},
+		{Floor, map[string]RoundingMode{"Military": Ceil}, 3, -3},
+		{Round, map[string]RoundingMode{"Money": Truncate}, 3, -4},
+	}
+	for _, test := range tests {
+		world := World{
+			Resources:     map[string]int{"Money": 7},
+			Powers:        map[string]int{"Military": 7},
+			Rounding:      test.rounding,
+			RoundingByKey: test.byKey,
+		}
+		if err := world.Apply(halve, nil); err != nil {
+			t.Fatal(err)
+		}
+		if world.Resources["Money"] != test.money || world.Powers["Military"] != test.military {
+			t.Errorf("%v %v: got Money %v and Military %v, want %v and %v",
+				test.rounding, test.byKey, world.Resources["Money"], world.Powers["Military"], test.money, test.military)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// savedState is the serialized form of an Engine.
+type savedState struct {
+	World World `json:"world"`
+	// Seed and Draws reproduce the random number generator as it was
+	// before the current decisions were offered.
+	Seed      int64          `json:"seed"`
+	Draws     uint64         `json:"draws"`
+	LastFired map[int]int    `json:"lastFired"`
+	Fires     map[int]int    `json:"fires,omitempty"`
+	Chosen    []ChosenChoice `json:"chosen,omitempty"`
+	Result    *GameResult    `json:"result,omitempty"`
+}
+
+// SaveState serializes the game so that it can be resumed with LoadState.
+// The undo history and the past worlds seen by the history guard function
+// aren't saved, nor are a pending follow-up decision and decisions kept on
+// offer until they expire: the game resumes with the turn's regular
+// decisions instead.
+func (e *Engine) SaveState() ([]byte, error) {
+	return json.Marshal(e.saved())
+}
+
+func (e *Engine) saved() savedState {
+	return savedState{
+		World:     e.world,
+		Seed:      e.src.seed,
+		Draws:     e.offerDraws,
+		LastFired: e.state.LastFired,
+		Fires:     e.state.Fires,
+		Chosen:    e.state.Chosen,
+		Result:    e.result,
+	}
+}
+
+// LoadState resumes a game saved with SaveState. The scenario and cfg must
+// be the ones the game was started with; cfg.Seed is ignored. Resources and
+// powers are renamed according to scenario.Renamed, in case the scenario
+// changed since.
+func LoadState(data []byte, scenario Scenario, cfg GameConfig) (*Engine, error) {
+	var saved savedState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid saved state: %v", err)
+	}
+	return restore(saved, scenario, cfg)
+}
+
+func restore(saved savedState, scenario Scenario, cfg GameConfig) (*Engine, error) {
+	// Resources and powers are added to the maps by Apply, so they must
+	// exist even if missing from the saved state.
+	if saved.World.Resources == nil {
+		saved.World.Resources = make(map[string]int)
+	}
+	if saved.World.Powers == nil {
+		saved.World.Powers = make(map[string]int)
+	}
+	saved.World.rename(scenario.Renamed)
+
+	state := NewRuleState()
+	for i, turn := range saved.LastFired {
+		if i < 0 || i >= len(scenario.Rules) {
+			return nil, fmt.Errorf("invalid saved state: unknown rule %d", i)
+		}
+		state.LastFired[i] = turn
+	}
+	for i, n := range saved.Fires {
+		if i < 0 || i >= len(scenario.Rules) {
+			return nil, fmt.Errorf("invalid saved state: unknown rule %d", i)
+		}
+		state.Fires[i] = n
+	}
+	state.Chosen = saved.Chosen
+
+	e, err := newEngine(scenario, cfg, saved.World, state, newCountingSource(saved.Seed, saved.Draws))
+	if err != nil {
+		return nil, err
+	}
+	if saved.Result != nil {
+		e.result = saved.Result
+		e.decisions = nil
+	}
+	return e, nil
+}
+
+// rename moves the values of resources and powers from their old to their
+// new name, as given by renamed. A value already stored under the new name
+// is kept. Other keys are left as they are.
+func (w *World) rename(renamed map[string]string) {
+	for old, name := range renamed {
+		for _, values := range []map[string]int{w.Resources, w.Powers, w.LastChange} {
+			if value, ok := values[old]; ok {
+				if _, ok := values[name]; !ok {
+					values[name] = value
+				}
+				delete(values, old)
+			}
+		}
+		if value, ok := w.Reals[old]; ok {
+			if _, ok := w.Reals[name]; !ok {
+				w.Reals[name] = value
+			}
+			delete(w.Reals, old)
+		}
+	}
+}
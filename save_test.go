@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// randomScenario has rules offered at random, with cooldowns and random
+// deltas, so that replaying it depends on the whole engine state.
+func randomScenario(t testing.TB) Scenario {
+	t.Helper()
+	var scenario Scenario
+	for i := 0; i < 5; i++ {
+		decision := Decision{Description: fmt.Sprintf("Decision %d", i), Choices: []Choice{{
+			Description: "Accept",
+			Change:      Change{Resources: map[string]Delta{"Money": {-100, 100, float64(OpAddRandom)}}},
+		}}}
+		rule := mustRule(t, fmt.Sprintf("rule%d", i), "true", 0.5, decision)
+		rule.Cooldown = i
+		scenario.Rules = append(scenario.Rules, rule)
+	}
+	return scenario
+}
+
+// withoutPast returns w without the past worlds, which aren't saved.
+func withoutPast(w World) World {
+	w.past = nil
+	return w
+}
+
+func TestSaveAndLoadState(t *testing.T) {
+	scenario := randomScenario(t)
+	cfg := GameConfig{
+		Seed:             7,
+		FallbackDecision: &Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}},
+	}
+	uninterrupted, err := NewEngine(scenario, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 0; turn < 3; turn++ {
+		if err := uninterrupted.Choose(uninterrupted.Decisions()[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := uninterrupted.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := LoadState(data, scenario, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for turn := 3; turn < 8; turn++ {
+		want, got := uninterrupted.Decisions(), resumed.Decisions()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("turn %v: got decisions %v, want %v", turn, descriptions(got), descriptions(want))
+		}
+		if err := uninterrupted.Choose(want[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := resumed.Choose(got[0].Choices[0]); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(withoutPast(resumed.Current()), withoutPast(uninterrupted.Current())) {
+			t.Fatalf("turn %v: got world %+v, want %+v", turn, resumed.Current(), uninterrupted.Current())
+		}
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	scenario := randomScenario(t)
+	tests := []string{
+		`not json`,
+		`{"world": {}, "seed": 1, "lastFired": {"5": 1}}`,
+		`{"world": {}, "seed": 1, "lastFired": {"-1": 1}}`,
+	}
+	for _, data := range tests {
+		if _, err := LoadState([]byte(data), scenario, GameConfig{}); err == nil {
+			t.Errorf("%v: got no error", data)
+		}
+	}
+}
+
+func TestLoadStateRenamed(t *testing.T) {
+	tests := []struct {
+		name      string
+		renamed   map[string]string
+		resources map[string]int
+		powers    map[string]int
+	}{
+		{"no renames", nil, map[string]int{"Money": 4100}, map[string]int{"Military": 90, "Legislation": 10}},
+		{
+			"renamed resource",
+			map[string]string{"Money": "Treasury"},
+			map[string]int{"Treasury": 4100},
+			map[string]int{"Military": 90, "Legislation": 10},
+		},
+		{
+			"renamed power",
+			map[string]string{"Military": "Army", "Gold": "Silver"},
+			map[string]int{"Money": 4100},
+			map[string]int{"Army": 90, "Legislation": 10},
+		},
+	}
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustChoose(t, e, "Raise")
+	data, err := e.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		scenario := taxScenario(t)
+		scenario.Renamed = test.renamed
+		loaded, err := LoadState(data, scenario, GameConfig{})
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		world := loaded.Current()
+		if !reflect.DeepEqual(world.Resources, test.resources) || !reflect.DeepEqual(world.Powers, test.powers) {
+			t.Errorf("%v: got %v and %v, want %v and %v", test.name, world.Resources, world.Powers, test.resources, test.powers)
+		}
+	}
+}
+
+func TestWorldRename(t *testing.T) {
+	w := World{
+		Resources:  map[string]int{"Money": 100, "Treasury": 5},
+		Reals:      map[string]float64{"Approval": 0.5},
+		LastChange: map[string]int{"Money": -10},
+	}
+	w.rename(map[string]string{"Money": "Treasury", "Approval": "Popularity"})
+	want := World{
+		Resources:  map[string]int{"Treasury": 5},
+		Reals:      map[string]float64{"Popularity": 0.5},
+		LastChange: map[string]int{"Treasury": -10},
+	}
+	if !reflect.DeepEqual(w, want) {
+		t.Errorf("got %+v, want %+v keeping the value under the new name", w, want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// ScheduledEvent offers a decision on given turns regardless of the world,
+// e.g. an election at turn 20.
+type ScheduledEvent struct {
+	// Turn is the turn a one-time event is offered on.
+	Turn int
+	// Every, if set, makes the event recurring: it's offered on every turn
+	// that's a multiple of Every, except the first one, and Turn is
+	// ignored.
+	Every int
+	// After, if set, makes the event time-based: it's offered once, on the
+	// first turn starting at least After after the game started, as told by
+	// GameConfig.Clock, and Turn and Every are ignored.
+	After time.Duration
+	Decision
+}
+
+// Due reports whether the event is offered on turn. Time-based events are
+// never due on a given turn.
+func (e ScheduledEvent) Due(turn int) bool {
+	if e.After > 0 {
+		return false
+	}
+	if e.Every > 0 {
+		return turn > 0 && turn%e.Every == 0
+	}
+	return turn == e.Turn
+}
+
+// dueDecisions returns the decisions of the scheduled events due on the
+// world's turn or, for time-based ones, whose time came during span,
+// without the choices whose guard doesn't pass.
+func (s Scenario) dueDecisions(world World, span timeSpan) ([]Decision, error) {
+	var decisions []Decision
+	for _, event := range s.Scheduled {
+		if !event.Due(world.Turn) && !(event.After > 0 && span.contains(event.After)) {
+			continue
+		}
+		decision, err := event.Decision.availableChoices(world)
+		if err != nil {
+			return nil, err
+		}
+		if len(decision.Choices) > 0 {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScheduledEventDue(t *testing.T) {
+	tests := []struct {
+		event ScheduledEvent
+		want  []int
+	}{
+		{ScheduledEvent{Turn: 0}, []int{0}},
+		{ScheduledEvent{Turn: 3}, []int{3}},
+		{ScheduledEvent{Every: 3}, []int{3, 6, 9}},
+		{ScheduledEvent{Turn: 2, Every: 4}, []int{4, 8}},
+		{ScheduledEvent{Every: 1}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, test := range tests {
+		var got []int
+		for turn := 0; turn < 10; turn++ {
+			if test.event.Due(turn) {
+				got = append(got, turn)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%+v: got due on %v, want %v", test.event, got, test.want)
+		}
+	}
+}
+
+func TestScheduledDecisions(t *testing.T) {
+	accept := []Choice{{Description: "Accept"}}
+	scenario := Scenario{
+		Rules: []Rule{mustRule(t, "tax", "true", 1, Decision{Description: "Tax", Choices: accept})},
+		Scheduled: []ScheduledEvent{
+			{Turn: 2, Decision: Decision{Description: "Coronation", Choices: accept}},
+			{Every: 3, Decision: Decision{Description: "Election", Choices: accept}},
+		},
+	}
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		{3, []string{"Tax"}},
+		{3, []string{"Tax"}},
+		{3, []string{"Coronation", "Tax"}},
+		{3, []string{"Election", "Tax"}},
+		{1, []string{"Tax"}},
+		{1, []string{"Tax"}},
+		{1, []string{"Election"}},
+	}
+	decisions := scenario.Decisions(fixedRand(0), NewRuleState())
+	for turn, test := range tests {
+		got, err := decisions(World{Turn: turn}, test.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(descriptions(got)) != fmt.Sprint(test.want) {
+			t.Errorf("turn %v: got %v, want %v", turn, descriptions(got), test.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Selector picks at most max decisions to offer from candidates ranked
+// highest-weight first. Candidates of rules whose guard fails have a weight
+// of zero and shouldn't be offered.
+type Selector interface {
+	Select(candidates []CandidateDecision, max int, r Rand) []Decision
+}
+
+// SelectionMode determines how decisions are selected from the ranked
+// candidates.
+type SelectionMode int
+
+const (
+	// Independent offers each candidate with probability equal to its
+	// weight, so any number of decisions may be offered.
+	Independent SelectionMode = iota
+	// SingleWeighted offers exactly one passing candidate, picked at random
+	// with probability proportional to its weight.
+	SingleWeighted
+	// TopN offers the highest-weight passing candidates without drawing
+	// random numbers.
+	TopN
+)
+
+func (m SelectionMode) String() string {
+	switch m {
+	case Independent:
+		return "independent"
+	case SingleWeighted:
+		return "single-weighted"
+	case TopN:
+		return "top-n"
+	default:
+		return fmt.Sprintf("SelectionMode(%d)", int(m))
+	}
+}
+
+// Select implements Selector.
+func (m SelectionMode) Select(candidates []CandidateDecision, max int, r Rand) []Decision {
+	switch m {
+	case SingleWeighted:
+		return selectSingleWeighted(candidates, max, r)
+	case TopN:
+		return selectTopN(candidates, max)
+	default:
+		return selectIndependent(candidates, max, r, LinearProbability)
+	}
+}
+
+// ProbabilityFunc decides whether a candidate of the given weight is offered
+// in the Independent mode, given a random draw in [0, 1).
+type ProbabilityFunc func(weight float64, draw float64) bool
+
+// LinearProbability, the default, offers candidates with probability equal
+// to their weight.
+func LinearProbability(weight float64, draw float64) bool {
+	return draw < weight
+}
+
+// StepProbability returns a ProbabilityFunc offering the candidates whose
+// weight is at least threshold, regardless of the draw.
+func StepProbability(threshold float64) ProbabilityFunc {
+	return func(weight float64, draw float64) bool {
+		return weight >= threshold
+	}
+}
+
+// SigmoidProbability returns a ProbabilityFunc offering candidates with a
+// probability following a sigmoid curve around a weight of 0.5. The higher
+// steepness, the likelier weights above 0.5 are offered and the less likely
+// those below.
+func SigmoidProbability(steepness float64) ProbabilityFunc {
+	return func(weight float64, draw float64) bool {
+		return draw < 1/(1+math.Exp(-steepness*(weight-0.5)))
+	}
+}
+
+// probabilitySelector selects decisions like Independent, deciding with
+// accept instead.
+type probabilitySelector struct {
+	accept ProbabilityFunc
+}
+
+// Select implements Selector.
+func (s probabilitySelector) Select(candidates []CandidateDecision, max int, r Rand) []Decision {
+	return selectIndependent(candidates, max, r, s.accept)
+}
+
+// selectIndependent draws a number for each candidate until max are
+// offered, leaving out those with no weight whatever accept says.
+func selectIndependent(candidates []CandidateDecision, max int, r Rand, accept ProbabilityFunc) []Decision {
+	decisions := make([]Decision, 0, len(candidates))
+	for _, candidate := range candidates {
+		if len(decisions) >= max {
+			break
+		}
+		if draw := r.Float64(); candidate.Weight > 0 && accept(candidate.Weight, draw) {
+			decisions = append(decisions, candidate.Decision)
+		}
+	}
+	return decisions
+}
+
+func selectSingleWeighted(candidates []CandidateDecision, max int, r Rand) []Decision {
+	total := 0.0
+	for _, candidate := range candidates {
+		if candidate.Weight > 0 {
+			total += candidate.Weight
+		}
+	}
+	if max < 1 || total == 0 {
+		return nil
+	}
+	x := r.Float64() * total
+	last := 0
+	for i, candidate := range candidates {
+		if candidate.Weight <= 0 {
+			continue
+		}
+		if x < candidate.Weight {
+			return []Decision{candidate.Decision}
+		}
+		x -= candidate.Weight
+		last = i
+	}
+	// Guard against rounding errors.
+	return []Decision{candidates[last].Decision}
+}
+
+func selectTopN(candidates []CandidateDecision, max int) []Decision {
+	decisions := make([]Decision, 0, max)
+	for _, candidate := range candidates {
+		if len(decisions) >= max || candidate.Weight <= 0 {
+			break
+		}
+		decisions = append(decisions, candidate.Decision)
+	}
+	return decisions
+}
+
+// EpsilonGreedy offers the highest-weight passing candidates like TopN, but
+// fills each slot with a random passing candidate instead with probability
+// Epsilon, for variety.
+type EpsilonGreedy struct {
+	Epsilon float64
+}
+
+// Select implements Selector.
+func (g EpsilonGreedy) Select(candidates []CandidateDecision, max int, r Rand) []Decision {
+	remaining := make([]CandidateDecision, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Weight > 0 {
+			remaining = append(remaining, candidate)
+		}
+	}
+	decisions := make([]Decision, 0, len(remaining))
+	for len(decisions) < max && len(remaining) > 0 {
+		i := 0
+		if r.Float64() < g.Epsilon {
+			i = int(r.Float64() * float64(len(remaining)))
+		}
+		decisions = append(decisions, remaining[i].Decision)
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return decisions
+}
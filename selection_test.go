@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func rankedCandidates(weights ...float64) []CandidateDecision {
+	c := make([]CandidateDecision, len(weights))
+	for i, weight := range weights {
+		name := fmt.Sprintf("%v", weight)
+		c[i] = CandidateDecision{Rule: name, Weight: weight, Decision: Decision{Description: name}}
+	}
+	return c
+}
+
+func TestSingleWeightedDistribution(t *testing.T) {
+	const n = 30000
+	ranked := rankedCandidates(0.6, 0.3, 0.1, 0)
+	r := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		decisions := SingleWeighted.Select(ranked, 3, r)
+		if len(decisions) != 1 {
+			t.Fatalf("got %v decisions, want 1", len(decisions))
+		}
+		counts[decisions[0].Description]++
+	}
+	for _, c := range ranked {
+		got := float64(counts[c.Description]) / n
+		if math.Abs(got-c.Weight) > 0.02 {
+			t.Errorf("weight %v: got proportion %.3f", c.Weight, got)
+		}
+	}
+}
+
+func TestSelectionModes(t *testing.T) {
+	tests := []struct {
+		mode    SelectionMode
+		weights []float64
+		max     int
+		r       float64
+		want    []string
+	}{
+		{Independent, []float64{0.9, 0.5, 0.2}, 3, 0.4, []string{"0.9", "0.5"}},
+		{Independent, []float64{0.9, 0.5, 0.2}, 1, 0.1, []string{"0.9"}},
+		{SingleWeighted, []float64{0.5, 0.3, 0.2}, 3, 0.7, []string{"0.3"}},
+		{SingleWeighted, []float64{0.5, 0.3, 0.2}, 0, 0.7, []string{}},
+		{SingleWeighted, []float64{0, 0}, 3, 0.7, []string{}},
+		{TopN, []float64{0.9, 0.5, 0.2, 0}, 2, 0, []string{"0.9", "0.5"}},
+		{TopN, []float64{0.9, 0.5, 0.2, 0}, 5, 0, []string{"0.9", "0.5", "0.2"}},
+	}
+	for _, test := range tests {
+		got := descriptions(test.mode.Select(rankedCandidates(test.weights...), test.max, fixedRand(test.r)))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v of %v with max %v: got %v, want %v", test.mode, test.weights, test.max, got, test.want)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector Selector
+		max      int
+		r        Rand
+		want     []string
+	}{
+		{"top n", TopN, 2, fixedRand(0.5), []string{"0.9", "0.5"}},
+		{"greedy", EpsilonGreedy{Epsilon: 0}, 3, fixedRand(0.5), []string{"0.9", "0.5", "0.2"}},
+		{"random", EpsilonGreedy{Epsilon: 1}, 3, fixedRand(0.5), []string{"0.5", "0.2", "0.9"}},
+		{"random below epsilon", EpsilonGreedy{Epsilon: 0.6}, 2, fixedRand(0.5), []string{"0.5", "0.2"}},
+		{"greedy above epsilon", EpsilonGreedy{Epsilon: 0.4}, 2, fixedRand(0.5), []string{"0.9", "0.5"}},
+		{"max 0", EpsilonGreedy{Epsilon: 1}, 0, fixedRand(0.5), []string{}},
+		{"seeded", EpsilonGreedy{Epsilon: 0.5}, 3, rand.New(rand.NewSource(1)), []string{"0.9", "0.5", "0.2"}},
+	}
+	for _, test := range tests {
+		got := descriptions(test.selector.Select(rankedCandidates(0.9, 0.5, 0.2, 0), test.max, test.r))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestScenarioSelector(t *testing.T) {
+	scenario := Scenario{Mode: TopN, Selector: EpsilonGreedy{Epsilon: 1}}
+	for _, weight := range []float64{0.9, 0.5, 0.2} {
+		name := fmt.Sprint(weight)
+		decision := Decision{Description: name, Choices: []Choice{{Description: "Accept"}}}
+		scenario.Rules = append(scenario.Rules, mustRule(t, name, "true", weight, decision))
+	}
+	decisions, err := scenario.Decisions(fixedRand(0.5), NewRuleState())(World{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := descriptions(decisions); fmt.Sprint(got) != "[0.5]" {
+		t.Errorf("got %v, want [0.5] picked by the selector", got)
+	}
+}
+
+func TestProbabilityFuncs(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept ProbabilityFunc
+		r      float64
+		want   []string
+	}{
+		{"linear", LinearProbability, 0.4, []string{"0.9", "0.5"}},
+		{"linear high draw", LinearProbability, 0.95, []string{}},
+		{"step", StepProbability(0.5), 0.99, []string{"0.9", "0.5"}},
+		{"step low draw", StepProbability(0.5), 0, []string{"0.9", "0.5"}},
+		{"step above all", StepProbability(1), 0, []string{}},
+		{"sigmoid", SigmoidProbability(10), 0.5, []string{"0.9"}},
+		{"sigmoid low draw", SigmoidProbability(10), 0.01, []string{"0.9", "0.5", "0.2"}},
+	}
+	for _, test := range tests {
+		selector := probabilitySelector{test.accept}
+		got := descriptions(selector.Select(rankedCandidates(0.9, 0.5, 0.2, 0), 3, fixedRand(test.r)))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEngineProbability(t *testing.T) {
+	var scenario Scenario
+	for _, weight := range []float64{0.6, 0.4} {
+		name := fmt.Sprint(weight)
+		decision := Decision{Description: name, Choices: []Choice{{Description: "Accept"}}}
+		scenario.Rules = append(scenario.Rules, mustRule(t, name, "true", weight, decision))
+	}
+	for seed := int64(1); seed <= 10; seed++ {
+		e, err := NewEngine(scenario, GameConfig{Seed: seed, Probability: StepProbability(0.5)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := descriptions(e.Decisions()); fmt.Sprint(got) != "[0.6]" {
+			t.Errorf("seed %v: got %v, want [0.6]", seed, got)
+		}
+	}
+}
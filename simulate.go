@@ -0,0 +1,86 @@
+package main
+
+import "math/rand"
+
+// simulationMaxTurns caps the length of each simulated game so scenarios
+// that never end can still be simulated.
+const simulationMaxTurns = 100
+
+// SimulationReport aggregates statistics over simulated games.
+type SimulationReport struct {
+	Runs int
+	// AverageTurns is the average number of turns played per game.
+	AverageTurns float64
+	// FinalResources and FinalPowers hold the final value of each resource
+	// and power, one entry per game that ended with it set.
+	FinalResources map[string][]int
+	FinalPowers    map[string][]int
+	// RuleFired counts how many times a decision of each rule was chosen.
+	RuleFired map[string]int
+	// Stuck is the number of games that ended because no decision was
+	// offered.
+	Stuck int
+	// Errors is the number of games aborted because of an error, e.g. a
+	// guard failing to evaluate.
+	Errors int
+}
+
+// Simulate plays runs games of scenario making random choices and reports
+// how they went. Games are cut short after simulationMaxTurns turns. The
+// same seed always produces the same report.
+func Simulate(scenario Scenario, runs int, seed int64) SimulationReport {
+	report := SimulationReport{
+		Runs:           runs,
+		FinalResources: make(map[string][]int),
+		FinalPowers:    make(map[string][]int),
+		RuleFired:      make(map[string]int),
+	}
+	r := rand.New(rand.NewSource(seed))
+	turns := 0
+	for i := 0; i < runs; i++ {
+		engine, err := NewEngine(scenario, GameConfig{Seed: r.Int63(), MaxTurns: simulationMaxTurns})
+		if err != nil {
+			report.Errors++
+			continue
+		}
+		if err := simulateGame(engine, r, &report); err != nil {
+			report.Errors++
+		}
+		world := engine.Current()
+		turns += world.Turn
+		for k, v := range world.Resources {
+			report.FinalResources[k] = append(report.FinalResources[k], v)
+		}
+		for k, v := range world.Powers {
+			report.FinalPowers[k] = append(report.FinalPowers[k], v)
+		}
+	}
+	if runs > 0 {
+		report.AverageTurns = float64(turns) / float64(runs)
+	}
+	return report
+}
+
+func simulateGame(engine *Engine, r *rand.Rand, report *SimulationReport) error {
+	for {
+		decisions := engine.Decisions()
+		if len(decisions) == 0 {
+			if engine.Result() == nil {
+				report.Stuck++
+			}
+			return nil
+		}
+		decision := decisions[r.Intn(len(decisions))]
+		if len(decision.Choices) == 0 {
+			report.Stuck++
+			return nil
+		}
+		choice := decision.Choices[r.Intn(len(decision.Choices))]
+		if err := engine.Choose(choice); err != nil {
+			return err
+		}
+		if decision.Rule != "" {
+			report.RuleFired[decision.Rule]++
+		}
+	}
+}
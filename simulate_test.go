@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	once := func(name string, guard string) Rule {
+		decision := Decision{Description: name, Choices: []Choice{
+			{Description: "Raise", Change: Change{Resources: map[string]Delta{"Money": {100, 0, float64(OpAdd)}}}},
+			{Description: "Lower", Change: Change{Resources: map[string]Delta{"Money": {-100, 0, float64(OpAdd)}}}},
+		}}
+		rule := mustRule(t, name, guard, 1, decision)
+		rule.Once = true
+		return rule
+	}
+	tests := []struct {
+		name     string
+		scenario Scenario
+		runs     int
+		fired    map[string]int
+		turns    float64
+		stuck    int
+	}{
+		{
+			name:     "no rules",
+			scenario: Scenario{},
+			runs:     3,
+			fired:    map[string]int{},
+			turns:    0,
+			stuck:    3,
+		},
+		{
+			name:     "one rule",
+			scenario: Scenario{Rules: []Rule{once("a", "true")}},
+			runs:     5,
+			fired:    map[string]int{"a": 5},
+			turns:    1,
+			stuck:    5,
+		},
+		{
+			name:     "rules in sequence",
+			scenario: Scenario{Rules: []Rule{once("a", "true"), once("b", "World.Turn >= 1"), once("c", "World.Turn >= 5")}},
+			runs:     10,
+			fired:    map[string]int{"a": 10, "b": 10},
+			turns:    2,
+			stuck:    10,
+		},
+	}
+	for _, test := range tests {
+		report := Simulate(test.scenario, test.runs, 1)
+		if report.Runs != test.runs || report.Errors != 0 {
+			t.Errorf("%v: got %v runs and %v errors, want %v and 0", test.name, report.Runs, report.Errors, test.runs)
+		}
+		if !reflect.DeepEqual(report.RuleFired, test.fired) {
+			t.Errorf("%v: got rules fired %v, want %v", test.name, report.RuleFired, test.fired)
+		}
+		if report.AverageTurns != test.turns {
+			t.Errorf("%v: got %v turns on average, want %v", test.name, report.AverageTurns, test.turns)
+		}
+		if report.Stuck != test.stuck {
+			t.Errorf("%v: got %v stuck games, want %v", test.name, report.Stuck, test.stuck)
+		}
+		if got := len(report.FinalResources["Money"]); got != test.runs {
+			t.Errorf("%v: got %v final Money values, want %v", test.name, got, test.runs)
+		}
+	}
+}
+
+func TestSimulateSeed(t *testing.T) {
+	scenario := randomScenario(t)
+	if a, b := Simulate(scenario, 20, 3), Simulate(scenario, 20, 3); !reflect.DeepEqual(a, b) {
+		t.Errorf("got different reports for the same seed: %+v and %+v", a, b)
+	}
+}
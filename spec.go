@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TestSpec describes a scripted game and the world it must end in, for
+// scenario authors to check their scenarios in CI.
+type TestSpec struct {
+	// Scenario is the path of the scenario file.
+	Scenario string `json:"scenario"`
+	// Seed seeds the game. It's required, as a zero seed would seed it from
+	// the clock.
+	Seed int64 `json:"seed"`
+	// Choices are the indices of the choices made each turn, numbering the
+	// choices across all offered decisions.
+	Choices []int `json:"choices"`
+	// Expect lists the expected final values. Resources and powers left out
+	// aren't checked.
+	Expect struct {
+		Resources map[string]int `json:"resources"`
+		Powers    map[string]int `json:"powers"`
+	} `json:"expect"`
+}
+
+// LoadSpec reads a test spec from a JSON file. A relative scenario path is
+// resolved against the spec's directory.
+func LoadSpec(path string) (TestSpec, error) {
+	var spec TestSpec
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return spec, fmt.Errorf("%v: %v", path, err)
+	}
+	if spec.Scenario != "" && !filepath.IsAbs(spec.Scenario) {
+		spec.Scenario = filepath.Join(filepath.Dir(path), spec.Scenario)
+	}
+	return spec, nil
+}
+
+// start loads the scenario of spec and starts its game.
+func (spec TestSpec) start() (Scenario, *Engine, error) {
+	if spec.Seed == 0 {
+		return Scenario{}, nil, fmt.Errorf("no seed")
+	}
+	scenario, err := loadScenarioPath(spec.Scenario)
+	if err != nil {
+		return Scenario{}, nil, err
+	}
+	e, err := NewEngine(scenario, GameConfig{Seed: spec.Seed})
+	if err != nil {
+		return Scenario{}, nil, err
+	}
+	return scenario, e, nil
+}
+
+// RunSpec plays the game described by spec and returns the final world. It
+// fails if the spec has no seed, a choice can't be made or the final world
+// isn't as expected.
+func RunSpec(spec TestSpec) (World, error) {
+	_, e, err := spec.start()
+	if err != nil {
+		return World{}, err
+	}
+	for i, index := range spec.Choices {
+		if err := chooseIndex(e, index); err != nil {
+			return e.Current(), fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+
+	world := e.Current()
+	var mismatches []string
+	for _, expected := range []struct {
+		kind         string
+		want, actual map[string]int
+	}{{"resource", spec.Expect.Resources, world.Resources}, {"power", spec.Expect.Powers, world.Powers}} {
+		for key, want := range expected.want {
+			if actual, ok := expected.actual[key]; !ok || actual != want {
+				mismatches = append(mismatches, fmt.Sprintf("%v %v is %v, want %v", expected.kind, key, actual, want))
+			}
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return world, fmt.Errorf("%v", strings.Join(mismatches, "; "))
+	}
+	return world, nil
+}
+
+// replaySpec shows the game scripted by the spec at path in replayUI.
+func replaySpec(path string) error {
+	spec, err := LoadSpec(path)
+	if err != nil {
+		return err
+	}
+	scenario, e, err := spec.start()
+	if err != nil {
+		return err
+	}
+	choices := make([]Choice, len(spec.Choices))
+	for i, index := range spec.Choices {
+		choice, ok := choiceAt(e.Decisions(), index)
+		if !ok {
+			return fmt.Errorf("step %d: no choice %d", i, index)
+		}
+		if err := e.Choose(choice); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		choices[i] = choice
+	}
+	replayUI(scenario, spec.Seed, choices)
+	return nil
+}
+
+// runSpecs runs the specs in the given files, printing a line per spec,
+// and reports whether they all passed.
+func runSpecs(paths []string) bool {
+	ok := true
+	for _, path := range paths {
+		spec, err := LoadSpec(path)
+		if err == nil {
+			_, err = RunSpec(spec)
+		}
+		if err != nil {
+			fmt.Printf("FAIL %v: %v\n", path, err)
+			ok = false
+			continue
+		}
+		fmt.Printf("ok   %v\n", path)
+	}
+	return ok
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLoadAndRunSpec(t *testing.T) {
+	spec, err := LoadSpec("scenarios/simple_spec.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Scenario != "scenarios/simple.json" {
+		t.Errorf("got scenario %q, want it relative to the spec", spec.Scenario)
+	}
+	world, err := RunSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world.Resources["Money"] != 1000 || world.Turn != 2 {
+		t.Errorf("got %v, want Money 1000 on turn 2", world)
+	}
+}
+
+func TestRunSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{
+			name: "expected world",
+			spec: `{"scenario": "scenarios/simple.json", "seed": 1, "choices": [0, 0],
+				"expect": {"resources": {"Money": 1000}, "powers": {"Legislation": 100}}}`,
+		},
+		{
+			name: "no expectations",
+			spec: `{"scenario": "scenarios/simple.yaml", "seed": 1, "choices": [0]}`,
+		},
+		{
+			name: "unexpected world",
+			spec: `{"scenario": "scenarios/simple.json", "seed": 1, "choices": [0, 0],
+				"expect": {"resources": {"Money": 999, "Gold": 0}, "powers": {"Military": 90}}}`,
+			err: "resource Gold is 0, want 0; resource Money is 1000, want 999",
+		},
+		{
+			name: "invalid choice",
+			spec: `{"scenario": "scenarios/simple.json", "seed": 1, "choices": [0, 7]}`,
+			err:  "step 1: ",
+		},
+		{
+			name: "missing scenario",
+			spec: `{"scenario": "scenarios/missing.json", "seed": 1}`,
+			err:  "open scenarios/missing.json",
+		},
+		{
+			name: "no seed",
+			spec: `{"scenario": "scenarios/simple.json", "choices": [0]}`,
+			err:  "no seed",
+		},
+	}
+	for _, test := range tests {
+		var spec TestSpec
+		if err := json.Unmarshal([]byte(test.spec), &spec); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		_, err := RunSpec(spec)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%v: got error %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Store persists games between requests, e.g. for a multiplayer server.
+type Store interface {
+	Save(id string, e *Engine) error
+	// Load resumes the game saved under id.
+	Load(id string) (*Engine, error)
+}
+
+// MemoryStore is a Store keeping saved games in memory. It's safe for
+// concurrent use.
+type MemoryStore struct {
+	scenario Scenario
+	cfg      GameConfig
+
+	mu    sync.Mutex
+	games map[string][]byte
+}
+
+// NewMemoryStore returns an empty store for games of scenario played with
+// cfg.
+func NewMemoryStore(scenario Scenario, cfg GameConfig) *MemoryStore {
+	return &MemoryStore{
+		scenario: scenario,
+		cfg:      cfg,
+		games:    make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Save(id string, e *Engine) error {
+	data, err := e.SaveState()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games[id] = data
+	return nil
+}
+
+func (s *MemoryStore) Load(id string) (*Engine, error) {
+	s.mu.Lock()
+	data, ok := s.games[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no game %q", id)
+	}
+	return LoadState(data, s.scenario, s.cfg)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	scenario := randomScenario(t)
+	cfg := GameConfig{
+		Seed:             7,
+		FallbackDecision: &Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}},
+	}
+	store := NewMemoryStore(scenario, cfg)
+
+	tests := []struct {
+		id    string
+		turns int
+	}{
+		{"alice", 2},
+		{"bob", 5},
+		{"alice", 3},
+	}
+	want := make(map[string]*Engine)
+	for _, test := range tests {
+		e, err := NewEngine(scenario, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for turn := 0; turn < test.turns; turn++ {
+			if err := e.Choose(e.Decisions()[0].Choices[0]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.Save(test.id, e); err != nil {
+			t.Fatalf("%v: %v", test.id, err)
+		}
+		want[test.id] = e
+	}
+
+	for id, e := range want {
+		loaded, err := store.Load(id)
+		if err != nil {
+			t.Fatalf("%v: %v", id, err)
+		}
+		if !reflect.DeepEqual(withoutPast(loaded.Current()), withoutPast(e.Current())) {
+			t.Errorf("%v: got world %v, want %v", id, loaded.Current(), e.Current())
+		}
+		if !reflect.DeepEqual(loaded.Decisions(), e.Decisions()) {
+			t.Errorf("%v: got decisions %v, want %v", id, descriptions(loaded.Decisions()), descriptions(e.Decisions()))
+		}
+	}
+
+	if _, err := store.Load("carol"); err == nil {
+		t.Errorf("got no error loading a game that wasn't saved")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "sort"
+
+// ScenarioSummary describes a scenario at a glance, e.g. for a menu.
+type ScenarioSummary struct {
+	Rules int
+	// Keys are the sorted resources and powers referred to by the guards of
+	// the rules through World or changed by choices.
+	Keys []string
+	// MinWeight and MaxWeight are the bounds of the weights of the rules,
+	// not counting those computed by WeightExpr. Both are 0 if there are
+	// no such rules.
+	MinWeight float64
+	MaxWeight float64
+	// Resources and Powers are the sorted keys declared by the initial
+	// world. Resources include real and derived ones.
+	Resources []string
+	Powers    []string
+	// Likely are the descriptions of the decisions most likely to be
+	// offered on the first turn: those of the scheduled events due, the
+	// mandatory rules and then the highest-weight rules passing in the
+	// initial world, up to the default number of decisions per turn. It's
+	// nil if a guard fails to evaluate.
+	Likely []string
+}
+
+// Summary summarizes s without playing it.
+func (s Scenario) Summary() ScenarioSummary {
+	keys := s.changedKeys()
+	initial := s.initialWorld()
+	summary := ScenarioSummary{
+		Rules:     len(s.Rules),
+		Resources: sortedIntKeys(initial.Resources),
+		Powers:    sortedIntKeys(initial.Powers),
+	}
+	summary.Resources = append(summary.Resources, sortedFloatKeys(initial.Reals)...)
+	summary.Resources = append(summary.Resources, sortedStringKeys(initial.Derived)...)
+	sort.Strings(summary.Resources)
+	weighted := false
+	for _, rule := range s.Rules {
+		for _, key := range referencedKeys(rule.Source) {
+			keys[key] = true
+		}
+		if rule.WeightExpr != nil {
+			continue
+		}
+		if !weighted || rule.Weight < summary.MinWeight {
+			summary.MinWeight = rule.Weight
+		}
+		if !weighted || rule.Weight > summary.MaxWeight {
+			summary.MaxWeight = rule.Weight
+		}
+		weighted = true
+	}
+	summary.Keys = make([]string, 0, len(keys))
+	for key := range keys {
+		summary.Keys = append(summary.Keys, key)
+	}
+	sort.Strings(summary.Keys)
+	summary.Likely = s.likelyDecisions(initial)
+	return summary
+}
+
+// likelyDecisions returns the descriptions of the decisions offered in
+// world if the highest-weight rules were always selected.
+func (s Scenario) likelyDecisions(world World) []string {
+	if err := world.updateDerived(); err != nil {
+		return nil
+	}
+	preview := s
+	preview.Selector = TopN
+	decisions, err := preview.decisions(nil, NewRuleState(), world, defaultMaxDecisions, timeSpan{}, nil)
+	if err != nil {
+		return nil
+	}
+	likely := make([]string, len(decisions))
+	for i, decision := range decisions {
+		likely[i] = decision.Description
+	}
+	return likely
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	scenario, err := defaultScenario()
+	if err != nil {
+		t.Fatal(err)
+	}
+	weighted := mustRule(t, "weighted", "World.Reals.Approval > 50", 0, Decision{Choices: []Choice{{Description: "Accept"}}})
+	weighted.WeightExpr, err = NewNumberExpr("0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accept := []Choice{{Description: "Accept"}}
+	ranked := Scenario{
+		InitialWorld: &World{
+			Resources: map[string]int{"Money": 100},
+			Reals:     map[string]float64{"Approval": 0.5},
+		},
+		Rules: []Rule{
+			mustRule(t, "low", "true", 0.2, Decision{Description: "Low", Choices: accept}),
+			mustRule(t, "broke", "World.Resources.Money < 50", 1, Decision{Description: "Broke", Choices: accept}),
+			mustRule(t, "high", "true", 0.9, Decision{Description: "High", Choices: accept}),
+			mustRule(t, "middle", "true", 0.5, Decision{Description: "Middle", Choices: accept}),
+			mustRule(t, "lowest", "true", 0.1, Decision{Description: "Lowest", Choices: accept}),
+		},
+	}
+	tests := []struct {
+		name     string
+		scenario Scenario
+		want     string
+	}{
+		{
+			"sample", scenario,
+			"{Rules:2 Keys:[Legislation Military Money Popularity] MinWeight:1 MaxWeight:1 Resources:[Money Popularity] Powers:[Legislation Military] Likely:[Make putsch Quit]}",
+		},
+		{
+			"empty", Scenario{},
+			"{Rules:0 Keys:[] MinWeight:0 MaxWeight:0 Resources:[Money] Powers:[Legislation Military] Likely:[]}",
+		},
+		{
+			"weight expression", Scenario{Rules: []Rule{weighted}},
+			"{Rules:1 Keys:[Approval] MinWeight:0 MaxWeight:0 Resources:[Money] Powers:[Legislation Military] Likely:[]}",
+		},
+		{
+			"ranked", ranked,
+			"{Rules:5 Keys:[Money] MinWeight:0.1 MaxWeight:1 Resources:[Approval Money] Powers:[] Likely:[High Middle Low]}",
+		},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprintf("%+v", test.scenario.Summary()); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+// Decisions is a list of decisions, e.g. those offered for a turn.
+type Decisions []Decision
+
+// GroupByTag groups decisions by tag. A decision with several tags appears
+// in each of their groups; untagged decisions are grouped under "".
+func (ds Decisions) GroupByTag() map[string][]Decision {
+	groups := make(map[string][]Decision)
+	for _, d := range ds {
+		if len(d.Tags) == 0 {
+			groups[""] = append(groups[""], d)
+			continue
+		}
+		for _, tag := range d.Tags {
+			groups[tag] = append(groups[tag], d)
+		}
+	}
+	return groups
+}
+
+// HasAnyTag reports whether the decision has at least one of tags.
+func (d Decision) HasAnyTag(tags []string) bool {
+	for _, tag := range d.Tags {
+		for _, t := range tags {
+			if tag == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterByTags returns the decisions having at least one of tags, or all
+// of them if tags is empty.
+func filterByTags(decisions []Decision, tags []string) []Decision {
+	if len(tags) == 0 {
+		return decisions
+	}
+	filtered := make([]Decision, 0, len(decisions))
+	for _, d := range decisions {
+		if d.HasAnyTag(tags) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
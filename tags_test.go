@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// taggedDecisions returns decisions with one, several or no tags.
+func taggedDecisions() Decisions {
+	return Decisions{
+		{Description: "Tax", Tags: []string{"Economy"}, Choices: []Choice{{Description: "Accept"}}},
+		{Description: "Draft", Tags: []string{"Military"}, Choices: []Choice{{Description: "Accept"}}},
+		{Description: "Arms deal", Tags: []string{"Economy", "Diplomacy"}, Choices: []Choice{{Description: "Accept"}}},
+		{Description: "Speech", Choices: []Choice{{Description: "Accept"}}},
+	}
+}
+
+func TestGroupByTag(t *testing.T) {
+	groups := taggedDecisions().GroupByTag()
+	want := map[string][]string{
+		"":          {"Speech"},
+		"Economy":   {"Tax", "Arms deal"},
+		"Military":  {"Draft"},
+		"Diplomacy": {"Arms deal"},
+	}
+	got := make(map[string][]string, len(groups))
+	for tag, decisions := range groups {
+		got[tag] = descriptions(decisions)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := (Decisions{}).GroupByTag(); len(got) != 0 {
+		t.Errorf("got %v for no decisions, want no groups", got)
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, []string{"Tax", "Draft", "Arms deal", "Speech"}},
+		{[]string{"Economy"}, []string{"Tax", "Arms deal"}},
+		{[]string{"Diplomacy", "Military"}, []string{"Draft", "Arms deal"}},
+		{[]string{"Culture"}, []string{}},
+	}
+	for _, test := range tests {
+		got := descriptions(filterByTags(taggedDecisions(), test.tags))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.tags, got, test.want)
+		}
+	}
+}
+
+func TestEngineTags(t *testing.T) {
+	var scenario Scenario
+	for _, decision := range taggedDecisions() {
+		scenario.Rules = append(scenario.Rules, mustRule(t, decision.Description, "true", 1, decision))
+	}
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, []string{"Arms deal", "Draft", "Speech", "Tax"}},
+		{[]string{"Economy"}, []string{"Arms deal", "Tax"}},
+		{[]string{"Culture"}, []string{"Pass turn"}},
+	}
+	for _, test := range tests {
+		fallback := Decision{Description: "Pass turn", Choices: []Choice{{Description: "Accept"}}}
+		e, err := NewEngine(scenario, GameConfig{Seed: 1, MaxDecisions: 4, Tags: test.tags, FallbackDecision: &fallback})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := descriptions(e.Decisions())
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.tags, got, test.want)
+		}
+	}
+}
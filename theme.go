@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	tui "github.com/marcusolsson/tui-go"
+)
+
+// Theme configures the look of the console UI.
+type Theme struct {
+	// Normal styles the resource and power status bars.
+	Normal tui.Style
+	// Selected styles the selected choice.
+	Selected tui.Style
+	// Warning styles resources at or below their low threshold.
+	Warning tui.Style
+	// Unaffordable styles choices that would overspend the budget.
+	Unaffordable tui.Style
+	// Increased and Decreased style resources and powers that went up or
+	// down with the last choice.
+	Increased tui.Style
+	Decreased tui.Style
+	// LowThresholds maps resources to the value at or below which they are
+	// shown as warnings.
+	LowThresholds map[string]int
+}
+
+var defaultTheme = Theme{
+	Normal:       tui.Style{Fg: tui.ColorWhite, Bg: tui.ColorBlue},
+	Selected:     tui.Style{Fg: tui.ColorBlack, Bg: tui.ColorYellow},
+	Warning:      tui.Style{Fg: tui.ColorWhite, Bg: tui.ColorRed, Bold: tui.DecorationOn},
+	Unaffordable: tui.Style{Fg: tui.ColorBlack, Bold: tui.DecorationOn},
+	Increased:    tui.Style{Fg: tui.ColorGreen, Bg: tui.ColorBlue, Bold: tui.DecorationOn},
+	Decreased:    tui.Style{Fg: tui.ColorRed, Bg: tui.ColorBlue, Bold: tui.DecorationOn},
+	LowThresholds: map[string]int{
+		"Money": 1000,
+	},
+}
+
+func (t Theme) tuiTheme() *tui.Theme {
+	theme := tui.NewTheme()
+	theme.SetStyle("statusbar", t.Normal)
+	theme.SetStyle("table.cell.selected", t.Selected)
+	theme.SetStyle("label.warning", t.Warning)
+	theme.SetStyle("label.unaffordable", t.Unaffordable)
+	theme.SetStyle("label.unchanged", t.Normal)
+	theme.SetStyle("label.increased", t.Increased)
+	theme.SetStyle("label.decreased", t.Decreased)
+	return theme
+}
+
+// warningKeys returns the sorted resources of world at or below their low
+// threshold.
+func warningKeys(world World, thresholds map[string]int) []string {
+	keys := make([]string, 0)
+	for key, threshold := range thresholds {
+		if value, ok := world.Resources[key]; ok && value <= threshold {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StyleKind selects how a resource or power is styled in the status bars.
+type StyleKind int
+
+const (
+	Unchanged StyleKind = iota
+	Increased
+	Decreased
+)
+
+// String returns the style name of k.
+func (k StyleKind) String() string {
+	switch k {
+	case Unchanged:
+		return "unchanged"
+	case Increased:
+		return "increased"
+	case Decreased:
+		return "decreased"
+	default:
+		return fmt.Sprintf("StyleKind(%d)", int(k))
+	}
+}
+
+// changeStyles maps each key of delta, e.g. World.LastChange, to the style
+// kind of its sign.
+func changeStyles(delta map[string]int) map[string]StyleKind {
+	styles := make(map[string]StyleKind, len(delta))
+	for key, d := range delta {
+		switch {
+		case d > 0:
+			styles[key] = Increased
+		case d < 0:
+			styles[key] = Decreased
+		default:
+			styles[key] = Unchanged
+		}
+	}
+	return styles
+}
+
+// setStatus replaces the labels in box with one label per entry of texts,
+// sorted by key and styled by styles.
+func setStatus(box *tui.Box, texts map[string]string, styles map[string]StyleKind) {
+	for box.Length() > 0 {
+		box.Remove(0)
+	}
+	for _, key := range sortedStringKeys(texts) {
+		label := tui.NewLabel(texts[key] + " ")
+		label.SetStyleName(styles[key].String())
+		box.Append(label)
+	}
+	box.Append(tui.NewSpacer())
+}
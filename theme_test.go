@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWarningKeys(t *testing.T) {
+	thresholds := map[string]int{"Money": 1000, "Food": 10, "Gold": 5}
+	tests := []struct {
+		resources map[string]int
+		want      []string
+	}{
+		{map[string]int{"Money": 4000, "Food": 50}, []string{}},
+		{map[string]int{"Money": 1000, "Food": 50}, []string{"Money"}},
+		{map[string]int{"Money": -5, "Food": 3}, []string{"Food", "Money"}},
+		// Resources missing from the world aren't warned about.
+		{map[string]int{}, []string{}},
+		// Nor are resources without a threshold.
+		{map[string]int{"Oil": 0}, []string{}},
+	}
+	for _, test := range tests {
+		got := warningKeys(World{Resources: test.resources}, thresholds)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.resources, got, test.want)
+		}
+	}
+}
+
+func TestChangeStyles(t *testing.T) {
+	tests := []struct {
+		delta map[string]int
+		want  map[string]StyleKind
+	}{
+		{nil, map[string]StyleKind{}},
+		{map[string]int{"Money": -2000}, map[string]StyleKind{"Money": Decreased}},
+		{
+			map[string]int{"Money": 100, "Food": -3, "Military": 0},
+			map[string]StyleKind{"Money": Increased, "Food": Decreased, "Military": Unchanged},
+		},
+	}
+	for _, test := range tests {
+		if got := changeStyles(test.delta); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.delta, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecisionTrace explains how the decisions of a turn were selected, to
+// answer why a decision did or didn't appear. Decisions filtered out by
+// GameConfig.Tags afterwards still count as offered.
+type DecisionTrace struct {
+	// Candidates are the decisions of the mandatory rules and then of the
+	// other rules that have cooled down, in ranking order. Rules whose guard
+	// fails have a weight of 0.
+	Candidates []CandidateTrace
+	// Draws are the random numbers drawn by the selector, in order.
+	Draws []float64
+}
+
+// CandidateTrace explains whether a candidate decision was offered.
+type CandidateTrace struct {
+	Rule      string
+	Weight    float64
+	Mandatory bool
+	// Draw is the random number the weight was tested against, if HasDraw
+	// is set. Only the Independent selection mode tests each candidate.
+	Draw    float64
+	HasDraw bool
+	Offered bool
+}
+
+func (c CandidateTrace) String() string {
+	outcome := "not offered"
+	if c.Offered {
+		outcome = "offered"
+	}
+	switch {
+	case c.Mandatory:
+		return fmt.Sprintf("%v: mandatory -> %v", c.Rule, outcome)
+	case c.HasDraw && c.Draw < c.Weight:
+		return fmt.Sprintf("%v: r.Float64()=%.2f < weight=%.2f -> %v", c.Rule, c.Draw, c.Weight, outcome)
+	case c.HasDraw:
+		return fmt.Sprintf("%v: r.Float64()=%.2f >= weight=%.2f -> %v", c.Rule, c.Draw, c.Weight, outcome)
+	default:
+		return fmt.Sprintf("%v: weight=%.2f -> %v", c.Rule, c.Weight, outcome)
+	}
+}
+
+func (t DecisionTrace) String() string {
+	lines := make([]string, len(t.Candidates))
+	for i, candidate := range t.Candidates {
+		lines[i] = candidate.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newDecisionTrace traces the selection of offered from the mandatory and
+// other candidates. If pairDraws is set, the i-th draw was tested against
+// the i-th candidate.
+func newDecisionTrace(mandatory, candidates []CandidateDecision, offered []Decision, draws []float64, pairDraws bool) DecisionTrace {
+	trace := DecisionTrace{Draws: draws}
+	isOffered := func(candidate CandidateDecision) bool {
+		for _, decision := range offered {
+			if decision.Rule == candidate.Rule && decision.Description == candidate.Description {
+				return true
+			}
+		}
+		return false
+	}
+	for _, candidate := range mandatory {
+		trace.Candidates = append(trace.Candidates, CandidateTrace{
+			Rule:      candidate.Rule,
+			Weight:    candidate.Weight,
+			Mandatory: true,
+			Offered:   isOffered(candidate),
+		})
+	}
+	for i, candidate := range candidates {
+		c := CandidateTrace{
+			Rule:    candidate.Rule,
+			Weight:  candidate.Weight,
+			Offered: isOffered(candidate),
+		}
+		if pairDraws && i < len(draws) {
+			c.Draw, c.HasDraw = draws[i], true
+		}
+		trace.Candidates = append(trace.Candidates, c)
+	}
+	return trace
+}
+
+// recordingRand records the numbers drawn from Rand.
+type recordingRand struct {
+	Rand
+	draws []float64
+}
+
+func (r *recordingRand) Float64() float64 {
+	x := r.Rand.Float64()
+	r.draws = append(r.draws, x)
+	return x
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDecisionTrace(t *testing.T) {
+	rule := func(name, guard string, weight float64) Rule {
+		return mustRule(t, name, guard, weight, Decision{Description: name, Choices: []Choice{{Description: "Accept"}}})
+	}
+	coup := rule("coup", "true", 1)
+	coup.Mandatory = true
+	rules := []Rule{rule("tax", "true", 0.5), rule("war", "true", 0.2), rule("bribe", "false", 0.9), coup}
+	tests := []struct {
+		mode  SelectionMode
+		max   int
+		draws []float64
+		want  []string
+	}{
+		{
+			mode:  Independent,
+			max:   3,
+			draws: []float64{0.42, 0.42, 0.42},
+			want: []string{
+				"coup: mandatory -> offered",
+				"tax: r.Float64()=0.42 < weight=0.50 -> offered",
+				"war: r.Float64()=0.42 >= weight=0.20 -> not offered",
+				"bribe: r.Float64()=0.42 >= weight=0.00 -> not offered",
+			},
+		},
+		{
+			mode:  Independent,
+			max:   2,
+			draws: []float64{0.42},
+			want: []string{
+				"coup: mandatory -> offered",
+				"tax: r.Float64()=0.42 < weight=0.50 -> offered",
+				"war: weight=0.20 -> not offered",
+				"bribe: weight=0.00 -> not offered",
+			},
+		},
+		{
+			mode:  TopN,
+			max:   2,
+			draws: nil,
+			want: []string{
+				"coup: mandatory -> offered",
+				"tax: weight=0.50 -> offered",
+				"war: weight=0.20 -> not offered",
+				"bribe: weight=0.00 -> not offered",
+			},
+		},
+	}
+	for _, test := range tests {
+		scenario := Scenario{Rules: rules, Mode: test.mode}
+		var trace DecisionTrace
+		if _, err := scenario.decisions(fixedRand(0.42), NewRuleState(), World{}, test.max, timeSpan{}, &trace); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := trace.String(), strings.Join(test.want, "\n"); got != want {
+			t.Errorf("%v with max %v: got trace\n%v\nwant\n%v", test.mode, test.max, got, want)
+		}
+		if fmt.Sprint(trace.Draws) != fmt.Sprint(test.draws) {
+			t.Errorf("%v with max %v: got draws %v, want %v", test.mode, test.max, trace.Draws, test.draws)
+		}
+	}
+}
+
+func TestEngineDecisionTrace(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := e.DecisionTrace()
+	if len(trace.Candidates) != 1 || !trace.Candidates[0].Offered || !trace.Candidates[0].HasDraw {
+		t.Errorf("got trace %+v, want the tax rule tested and offered", trace)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationErrors lists all problems found in a scenario.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the scenario for mistakes: duplicate rule names, guards
+// that don't compile, weights outside [-1, 1], mandatory rules with
+// negative weights, decisions without choices, malformed deltas and, unless
+// DynamicKeys is set, changes to undeclared resources and powers, e.g.
+// misspelled ones or resources changed as powers, and decay of undeclared
+// keys. Follow-up decisions and default choices are checked too. All
+// problems found are returned as ValidationErrors.
+func (s Scenario) Validate() error {
+	var errs ValidationErrors
+	var declared *declaredKeys
+	if !s.DynamicKeys {
+		declared = s.declaredKeys()
+	}
+	names := make(map[string]int, len(s.Rules))
+	for i, rule := range s.Rules {
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("rule %d (%v): %v", i, rule.Name, fmt.Sprintf(format, args...)))
+		}
+
+		if j, ok := names[rule.Name]; ok {
+			fail("duplicate name, already used by rule %d", j)
+		} else {
+			names[rule.Name] = i
+		}
+		if _, err := NewGuardWithOptions(rule.Source, rule.GuardOptions); err != nil {
+			fail("invalid guard %q: %v", rule.Source, err)
+		}
+		if rule.Weight < -1 || rule.Weight > 1 {
+			fail("weight %v outside [-1, 1]", rule.Weight)
+		}
+		if rule.Mandatory && rule.Weight < 0 {
+			fail("mandatory with negative weight %v", rule.Weight)
+		}
+		// Rules with a negative weight are never offered.
+		if len(rule.Choices) == 0 && rule.Weight >= 0 {
+			fail("decision %q has no choices", rule.Description)
+		}
+		if rule.ExpiresInTurns < 0 {
+			fail("decision %q expires in negative turns %v", rule.Description, rule.ExpiresInTurns)
+		}
+		for _, err := range rule.Decision.check(declared) {
+			fail("%v", err)
+		}
+	}
+	for _, key := range sortedDeltaKeys(s.Decay) {
+		if err := s.Decay[key].check(); err != nil {
+			errs = append(errs, fmt.Errorf("decay of %v: %v", key, err))
+		}
+		if declared != nil && !declared.resources[key] && !declared.powers[key] {
+			errs = append(errs, fmt.Errorf("decay of unknown resource or power %v", key))
+		}
+	}
+	for i, event := range s.Scheduled {
+		if event.Every < 0 {
+			errs = append(errs, fmt.Errorf("scheduled event %d: negative interval %v", i, event.Every))
+		}
+		if event.After < 0 {
+			errs = append(errs, fmt.Errorf("scheduled event %d: negative time %v", i, event.After))
+		}
+		if len(event.Choices) == 0 {
+			errs = append(errs, fmt.Errorf("scheduled event %d: decision %q has no choices", i, event.Description))
+		}
+		for _, err := range event.Decision.check(declared) {
+			errs = append(errs, fmt.Errorf("scheduled event %d: %v", i, err))
+		}
+	}
+	for i, decision := range s.AlwaysAvailable {
+		if len(decision.Choices) == 0 {
+			errs = append(errs, fmt.Errorf("always available decision %d: decision %q has no choices", i, decision.Description))
+		}
+		for _, err := range decision.check(declared) {
+			errs = append(errs, fmt.Errorf("always available decision %d: %v", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check returns the problems with the changes of d's choices, including
+// their branches and follow-up decisions, and of its default choice.
+func (d Decision) check(declared *declaredKeys) []error {
+	var errs []error
+	checkChoice := func(prefix string, choice Choice) {
+		for _, err := range choice.Change.check(declared) {
+			errs = append(errs, fmt.Errorf("%v %q: %v", prefix, choice.Description, err))
+		}
+		for j, branch := range choice.Branches {
+			for _, err := range branch.Change.check(declared) {
+				errs = append(errs, fmt.Errorf("%v %q: branch %d: %v", prefix, choice.Description, j, err))
+			}
+		}
+		if choice.Next == nil {
+			return
+		}
+		if len(choice.Next.Choices) == 0 {
+			errs = append(errs, fmt.Errorf("%v %q: follow-up decision %q has no choices", prefix, choice.Description, choice.Next.Description))
+		}
+		for _, err := range choice.Next.check(declared) {
+			errs = append(errs, fmt.Errorf("%v %q: follow-up: %v", prefix, choice.Description, err))
+		}
+	}
+	for _, choice := range d.Choices {
+		checkChoice("choice", choice)
+	}
+	if d.Default != nil {
+		checkChoice("default", *d.Default)
+	}
+	return errs
+}
+
+// check returns the malformed deltas of c and, unless declared is nil, the
+// keys it changes that aren't declared as resources or powers respectively.
+func (c Change) check(declared *declaredKeys) []error {
+	var errs []error
+	for _, key := range sortedDeltaKeys(c.Resources) {
+		if err := c.Resources[key].check(); err != nil {
+			errs = append(errs, fmt.Errorf("resource %v: %v", key, err))
+		}
+		if declared == nil || declared.resources[key] {
+			continue
+		}
+		if declared.powers[key] {
+			errs = append(errs, fmt.Errorf("%v is a power, not a resource", key))
+		} else {
+			errs = append(errs, fmt.Errorf("unknown resource %v", key))
+		}
+	}
+	for _, key := range sortedDeltaKeys(c.Powers) {
+		if err := c.Powers[key].check(); err != nil {
+			errs = append(errs, fmt.Errorf("power %v: %v", key, err))
+		}
+		if declared == nil || declared.powers[key] {
+			continue
+		}
+		if declared.resources[key] {
+			errs = append(errs, fmt.Errorf("%v is a resource, not a power", key))
+		} else {
+			errs = append(errs, fmt.Errorf("unknown power %v", key))
+		}
+	}
+	return errs
+}
+
+// declaredKeys are the resources and powers declared by a scenario.
+type declaredKeys struct {
+	resources, powers map[string]bool
+}
+
+// declaredKeys returns the resources, including fractional and derived
+// ones, and powers of the initial world. Keys only described in Meta may be
+// either.
+func (s Scenario) declaredKeys() *declaredKeys {
+	declared := &declaredKeys{resources: make(map[string]bool), powers: make(map[string]bool)}
+	initial := s.initialWorld()
+	for key := range initial.Resources {
+		declared.resources[key] = true
+	}
+	for key := range initial.Reals {
+		declared.resources[key] = true
+	}
+	for key := range initial.Derived {
+		declared.resources[key] = true
+	}
+	for key := range initial.Powers {
+		declared.powers[key] = true
+	}
+	for key := range s.Meta {
+		if !declared.resources[key] && !declared.powers[key] {
+			declared.resources[key] = true
+			declared.powers[key] = true
+		}
+	}
+	return declared
+}
+
+// UnreachableRules returns the names of rules whose guard passes in none of
+// sampleWorlds, which should be representative of the worlds the scenario
+// is played in. Guards failing to evaluate, e.g. because they reference a
+// missing resource, count as not passing. Unnamed rules are reported by
+// index.
+func (s Scenario) UnreachableRules(sampleWorlds []World) []string {
+	var unreachable []string
+	for i, rule := range s.Rules {
+		if !rule.reachable(sampleWorlds) {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("rule %d", i)
+			}
+			unreachable = append(unreachable, name)
+		}
+	}
+	return unreachable
+}
+
+func (r Rule) reachable(worlds []World) bool {
+	for _, world := range worlds {
+		if pass, err := r.Pass(world); err == nil && pass {
+			return true
+		}
+	}
+	return false
+}
+
+// DeltaWarning reports a choice whose change would push a resource or power
+// out of its bounds.
+type DeltaWarning struct {
+	// Rule is the name of the rule, or "rule <index>" if it's unnamed, or
+	// "scheduled event <index>".
+	Rule   string
+	Choice string
+	// Key is the resource or power, and Value the unclamped value the
+	// change would give it.
+	Key     string
+	Value   float64
+	Message string
+}
+
+func (w DeltaWarning) String() string {
+	return fmt.Sprintf("%v: choice %q: %v", w.Rule, w.Choice, w.Message)
+}
+
+// AnalyzeDeltas applies each choice of the scenario once to initial and
+// reports the resources and powers that would end up outside of
+// initial.Bounds or negative despite initial.NonNegative, before being
+// clamped. Random deltas take their expected value. Choices whose branch
+// guards fail to evaluate are skipped.
+func (s Scenario) AnalyzeDeltas(initial World) []DeltaWarning {
+	var warnings []DeltaWarning
+	analyze := func(name string, decision Decision) {
+		for _, choice := range decision.Choices {
+			change, err := choice.change(initial)
+			if err != nil {
+				continue
+			}
+			for _, deltas := range []map[string]Delta{change.Powers, change.Resources} {
+				for _, key := range sortedDeltaKeys(deltas) {
+					value := deltas[key].apply(initial.value(key), expectedRand{})
+					if message := initial.outOfBounds(key, value); message != "" {
+						warnings = append(warnings, DeltaWarning{
+							Rule:    name,
+							Choice:  choice.Description,
+							Key:     key,
+							Value:   value,
+							Message: message,
+						})
+					}
+				}
+			}
+		}
+	}
+	for i, rule := range s.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+		analyze(name, rule.Decision)
+	}
+	for i, event := range s.Scheduled {
+		analyze(fmt.Sprintf("scheduled event %d", i), event.Decision)
+	}
+	return warnings
+}
+
+// outOfBounds describes how value violates the bounds of key in w, or
+// returns "" if it doesn't.
+func (w World) outOfBounds(key string, value float64) string {
+	if bounds, ok := w.Bounds[key]; ok {
+		if value < float64(bounds[0]) {
+			return fmt.Sprintf("%v would be %v, below its lower bound %v", key, value, bounds[0])
+		}
+		if value > float64(bounds[1]) {
+			return fmt.Sprintf("%v would be %v, above its upper bound %v", key, value, bounds[1])
+		}
+	}
+	if w.NonNegative[key] && value < 0 {
+		return fmt.Sprintf("%v would be %v, below zero", key, value)
+	}
+	return ""
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	accept := Choice{Description: "Accept"}
+	valid := func(name string) Rule {
+		return mustRule(t, name, "true", 1, Decision{Description: name, Choices: []Choice{accept}})
+	}
+	mandatory := mustRule(t, "a", "true", -0.5, Decision{Choices: []Choice{accept}})
+	mandatory.Mandatory = true
+	tests := []struct {
+		name  string
+		rules []Rule
+		errs  []string
+	}{
+		{
+			name:  "valid",
+			rules: []Rule{valid("a"), valid("b")},
+		},
+		{
+			name:  "duplicate name",
+			rules: []Rule{valid("a"), valid("b"), valid("a")},
+			errs:  []string{"rule 2 (a): duplicate name, already used by rule 0"},
+		},
+		{
+			name:  "invalid guard",
+			rules: []Rule{{Name: "a", Guard: Guard{Source: "World.Resources.Money >"}, Weight: 1, Decision: Decision{Choices: []Choice{accept}}}},
+			errs:  []string{`rule 0 (a): invalid guard "World.Resources.Money >"`},
+		},
+		{
+			name: "weight",
+			rules: []Rule{
+				mustRule(t, "a", "true", -1.1, Decision{Choices: []Choice{accept}}),
+				mustRule(t, "b", "true", 1.1, Decision{Choices: []Choice{accept}}),
+			},
+			errs: []string{"rule 0 (a): weight -1.1 outside [-1, 1]", "rule 1 (b): weight 1.1 outside [-1, 1]"},
+		},
+		{
+			name:  "mandatory with negative weight",
+			rules: []Rule{mandatory},
+			errs:  []string{"rule 0 (a): mandatory with negative weight -0.5"},
+		},
+		{
+			name:  "suppressing rule without choices",
+			rules: []Rule{valid("a"), mustRule(t, "b", "true", -0.5, Decision{Description: "Veto"})},
+		},
+		{
+			name:  "no choices",
+			rules: []Rule{mustRule(t, "a", "true", 1, Decision{Description: "Empty"})},
+			errs:  []string{`rule 0 (a): decision "Empty" has no choices`},
+		},
+		{
+			name: "malformed deltas",
+			rules: []Rule{mustRule(t, "a", "true", 1, Decision{Choices: []Choice{{
+				Description: "Accept",
+				Change: Change{
+					Resources: map[string]Delta{"Money": {1}},
+					Powers:    map[string]Delta{"Military": {1, 0, 42}},
+				},
+			}}})},
+			errs: []string{
+				`rule 0 (a): choice "Accept": resource Money: delta must have 2 or 3 elements, got 1`,
+				`rule 0 (a): choice "Accept": power Military: unknown delta op 42`,
+			},
+		},
+		{
+			name: "malformed branch deltas",
+			rules: []Rule{mustRule(t, "a", "true", 1, Decision{Choices: []Choice{{
+				Description: "Accept",
+				Branches: []Branch{{
+					Guard:  Guard{Source: "true"},
+					Change: Change{Resources: map[string]Delta{"Money": {1, 2, 3, 4}}},
+				}},
+			}}})},
+			errs: []string{`rule 0 (a): choice "Accept": branch 0: resource Money: delta must have 2 or 3 elements, got 4`},
+		},
+	}
+	for _, test := range tests {
+		err := Scenario{Rules: test.rules}.Validate()
+		if len(test.errs) == 0 {
+			if err != nil {
+				t.Errorf("%v: got error %v", test.name, err)
+			}
+			continue
+		}
+		errs, ok := err.(ValidationErrors)
+		if !ok || len(errs) != len(test.errs) {
+			t.Errorf("%v: got %v, want %v errors", test.name, err, len(test.errs))
+			continue
+		}
+		for i, want := range test.errs {
+			if !strings.HasPrefix(errs[i].Error(), want) {
+				t.Errorf("%v: got error %v, want %v", test.name, errs[i], want)
+			}
+		}
+	}
+}
+
+func TestValidateScheduled(t *testing.T) {
+	tests := []struct {
+		name  string
+		event ScheduledEvent
+		errs  []string
+	}{
+		{
+			name:  "valid",
+			event: ScheduledEvent{Every: 2, Decision: Decision{Choices: []Choice{{Description: "Accept"}}}},
+		},
+		{
+			name:  "negative interval",
+			event: ScheduledEvent{Every: -1, Decision: Decision{Choices: []Choice{{Description: "Accept"}}}},
+			errs:  []string{"scheduled event 0: negative interval -1"},
+		},
+		{
+			name:  "no choices",
+			event: ScheduledEvent{Turn: 3, Decision: Decision{Description: "Election"}},
+			errs:  []string{`scheduled event 0: decision "Election" has no choices`},
+		},
+		{
+			name: "malformed delta",
+			event: ScheduledEvent{Decision: Decision{Choices: []Choice{{
+				Description: "Accept",
+				Change:      Change{Resources: map[string]Delta{"Money": {1}}},
+			}}}},
+			errs: []string{`scheduled event 0: choice "Accept": resource Money: delta must have 2 or 3 elements, got 1`},
+		},
+	}
+	for _, test := range tests {
+		err := Scenario{Scheduled: []ScheduledEvent{test.event}}.Validate()
+		var got []string
+		if errs, ok := err.(ValidationErrors); ok {
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+		} else if err != nil {
+			got = []string{err.Error()}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.errs) {
+			t.Errorf("%v: got errors %q, want %q", test.name, got, test.errs)
+		}
+	}
+}
+
+func TestUnreachableRules(t *testing.T) {
+	worlds := []World{
+		{Resources: map[string]int{"Money": 500}, Powers: map[string]int{"Military": 10}},
+		{Resources: map[string]int{"Money": 2000}, Powers: map[string]int{"Military": 90}},
+	}
+	unnamed := mustRule(t, "", "false", 1, Decision{})
+	tests := []struct {
+		name   string
+		rules  []Rule
+		worlds []World
+		want   []string
+	}{
+		{
+			name: "reachable",
+			rules: []Rule{
+				mustRule(t, "rich", "World.Resources.Money > 1000", 1, Decision{}),
+				mustRule(t, "poor", "World.Resources.Money < 1000", 1, Decision{}),
+			},
+			worlds: worlds,
+			want:   nil,
+		},
+		{
+			name: "never passing",
+			rules: []Rule{
+				mustRule(t, "never", "false", 1, Decision{}),
+				mustRule(t, "always", "true", 1, Decision{}),
+				mustRule(t, "gold", "World.Resources.Gold > 0", 1, Decision{}),
+				mustRule(t, "contradiction", "World.Powers.Military > 50 and World.Resources.Money < 1000", 1, Decision{}),
+			},
+			worlds: worlds,
+			want:   []string{"never", "gold", "contradiction"},
+		},
+		{
+			name:   "unnamed",
+			rules:  []Rule{mustRule(t, "a", "true", 1, Decision{}), unnamed},
+			worlds: worlds,
+			want:   []string{"rule 1"},
+		},
+		{
+			name:   "no sample worlds",
+			rules:  []Rule{mustRule(t, "always", "true", 1, Decision{})},
+			worlds: nil,
+			want:   []string{"always"},
+		},
+	}
+	for _, test := range tests {
+		got := Scenario{Rules: test.rules}.UnreachableRules(test.worlds)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAnalyzeDeltas(t *testing.T) {
+	change := func(resource string, delta Delta) Change {
+		return Change{Resources: map[string]Delta{resource: delta}}
+	}
+	initial := World{
+		Resources:   map[string]int{"Money": 1000, "Food": 10},
+		Powers:      map[string]int{"Military": 90},
+		Bounds:      map[string][2]int{"Money": {500, 5000}, "Military": {0, 100}},
+		NonNegative: map[string]bool{"Food": true},
+	}
+	tests := []struct {
+		name   string
+		choice Choice
+		want   []string
+	}{
+		{"within bounds", Choice{Description: "Save", Change: change("Money", Delta{1, 100})}, []string{}},
+		{"busts the floor", Choice{Description: "Spend", Change: change("Money", Delta{0.4, 0})}, []string{`spend: choice "Spend": Money would be 400, below its lower bound 500`}},
+		{"busts the ceiling", Choice{Description: "Print", Change: change("Money", Delta{10, 0})}, []string{`spend: choice "Print": Money would be 10000, above its upper bound 5000`}},
+		{"negative", Choice{Description: "Feast", Change: change("Food", Delta{1, -20})}, []string{`spend: choice "Feast": Food would be -10, below zero`}},
+		{"powers", Choice{Description: "Recruit", Change: Change{Powers: map[string]Delta{"Military": {1, 20}}}}, []string{`spend: choice "Recruit": Military would be 110, above its upper bound 100`}},
+		{"unbounded", Choice{Description: "Tax", Change: change("Gold", Delta{1, -20})}, []string{}},
+	}
+	for _, test := range tests {
+		decision := Decision{Description: "Budget", Choices: []Choice{test.choice}}
+		scenario := Scenario{Rules: []Rule{mustRule(t, "spend", "true", 1, decision)}}
+		got := make([]string, 0)
+		for _, warning := range scenario.AnalyzeDeltas(initial) {
+			got = append(got, warning.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	scheduled := Scenario{Scheduled: []ScheduledEvent{{Turn: 1, Decision: Decision{Choices: []Choice{
+		{Description: "Spend", Change: change("Money", Delta{0, 0})},
+	}}}}}
+	warnings := scheduled.AnalyzeDeltas(initial)
+	if len(warnings) != 1 || warnings[0].Rule != "scheduled event 0" || warnings[0].Key != "Money" || warnings[0].Value != 0 {
+		t.Errorf("got %+v for a scheduled event, want a Money warning", warnings)
+	}
+}
+
+func TestValidateDecay(t *testing.T) {
+	tests := []struct {
+		decay map[string]Delta
+		err   string
+	}{
+		{map[string]Delta{"Money": {0.9, 0}}, ""},
+		{map[string]Delta{"Money": {0.9}}, "decay of Money: delta must have 2 or 3 elements, got 1"},
+		{map[string]Delta{"Military": {1, 0, 42}}, "decay of Military: unknown delta op 42"},
+	}
+	for _, test := range tests {
+		err := Scenario{Decay: test.decay}.Validate()
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%v: got error %v", test.decay, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %v", test.decay, err, test.err)
+		}
+	}
+}
+
+func TestValidateUndeclaredKeys(t *testing.T) {
+	accept := Choice{Description: "Accept"}
+	change := func(resources, powers map[string]Delta) Rule {
+		return mustRule(t, "a", "true", 1, Decision{Choices: []Choice{{
+			Description: "Accept",
+			Change:      Change{Resources: resources, Powers: powers},
+		}}})
+	}
+	initial := &World{Resources: map[string]int{"Money": 100, "Popularity": 0}, Powers: map[string]int{"Military": 50}}
+	tests := []struct {
+		name     string
+		scenario Scenario
+		errs     []string
+	}{
+		{
+			name:     "declared",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{change(map[string]Delta{"Popularity": {1, 5}}, map[string]Delta{"Military": {1, 5}})}},
+		},
+		{
+			name:     "misspelled resource",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{change(map[string]Delta{"Poplarity": {1, 5}, "Money": {1, 5}}, nil)}},
+			errs:     []string{`rule 0 (a): choice "Accept": unknown resource Poplarity`},
+		},
+		{
+			name:     "misspelled power",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{change(nil, map[string]Delta{"Militray": {1, 5}})}},
+			errs:     []string{`rule 0 (a): choice "Accept": unknown power Militray`},
+		},
+		{
+			name:     "resource changed as a power",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{change(nil, map[string]Delta{"Money": {1, 5}})}},
+			errs:     []string{`rule 0 (a): choice "Accept": Money is a resource, not a power`},
+		},
+		{
+			name:     "power changed as a resource",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{change(map[string]Delta{"Military": {1, 5}}, nil)}},
+			errs:     []string{`rule 0 (a): choice "Accept": Military is a power, not a resource`},
+		},
+		{
+			name: "power described in meta changed as a resource",
+			scenario: Scenario{
+				InitialWorld: initial,
+				Meta:         ResourceMeta{"Military": Percent},
+				Rules:        []Rule{change(map[string]Delta{"Military": {1, 5}}, nil)},
+			},
+			errs: []string{`rule 0 (a): choice "Accept": Military is a power, not a resource`},
+		},
+		{
+			name: "decay",
+			scenario: Scenario{
+				InitialWorld: initial,
+				Decay:        map[string]Delta{"Popularity": {0.9, 0}, "Military": {0.9, 0}, "Poplarity": {0.9, 0}},
+			},
+			errs: []string{"decay of unknown resource or power Poplarity"},
+		},
+		{
+			name: "declared in meta",
+			scenario: Scenario{
+				InitialWorld: initial,
+				Meta:         ResourceMeta{"Church": Integer},
+				Rules:        []Rule{change(nil, map[string]Delta{"Church": {1, 5}})},
+			},
+		},
+		{
+			name:     "dynamic keys",
+			scenario: Scenario{InitialWorld: initial, DynamicKeys: true, Rules: []Rule{change(map[string]Delta{"Poplarity": {1, 5}}, nil)}},
+		},
+		{
+			name: "scheduled",
+			scenario: Scenario{InitialWorld: initial, Scheduled: []ScheduledEvent{{Turn: 1, Decision: Decision{Choices: []Choice{{
+				Description: "Accept",
+				Change:      Change{Resources: map[string]Delta{"Gold": {1, 5}}},
+			}}}}}},
+			errs: []string{`scheduled event 0: choice "Accept": unknown resource Gold`},
+		},
+		{
+			name: "follow-up",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{mustRule(t, "a", "true", 1, Decision{Choices: []Choice{{
+				Description: "Accept",
+				Next: &Decision{Description: "How?", Choices: []Choice{{
+					Description: "Quietly",
+					Next: &Decision{Description: "When?", Choices: []Choice{{
+						Description: "Now",
+						Change:      Change{Resources: map[string]Delta{"Gold": {1, 5}}},
+					}}},
+				}}},
+			}}})}},
+			errs: []string{`rule 0 (a): choice "Accept": follow-up: choice "Quietly": follow-up: choice "Now": unknown resource Gold`},
+		},
+		{
+			name: "follow-up without choices",
+			scenario: Scenario{InitialWorld: initial, AlwaysAvailable: []Decision{{Description: "Act", Choices: []Choice{{
+				Description: "Accept",
+				Next:        &Decision{Description: "How?"},
+			}}}}},
+			errs: []string{`always available decision 0: choice "Accept": follow-up decision "How?" has no choices`},
+		},
+		{
+			name: "default",
+			scenario: Scenario{InitialWorld: initial, Rules: []Rule{mustRule(t, "a", "true", 1, Decision{
+				Choices: []Choice{accept},
+				Default: &Choice{Description: "Ignore", Change: Change{Powers: map[string]Delta{"Militray": {1, -5}}}},
+			})}},
+			errs: []string{`rule 0 (a): default "Ignore": unknown power Militray`},
+		},
+	}
+	for _, test := range tests {
+		err := test.scenario.Validate()
+		var got []string
+		if errs, ok := err.(ValidationErrors); ok {
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+		} else if err != nil {
+			got = []string{err.Error()}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.errs) {
+			t.Errorf("%v: got errors %q, want %q", test.name, got, test.errs)
+		}
+	}
+}
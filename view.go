@@ -0,0 +1,74 @@
+package main
+
+import "fmt"
+
+// stateView is the state of a game as shown to a remote player.
+type stateView struct {
+	World     World          `json:"world"`
+	Decisions []decisionView `json:"decisions"`
+	Result    *GameResult    `json:"result,omitempty"`
+}
+
+type decisionView struct {
+	Description string       `json:"description"`
+	Choices     []choiceView `json:"choices"`
+}
+
+// choiceView is a choice as shown to a remote player. Index numbers choices
+// across all offered decisions and is what the player sends back.
+type choiceView struct {
+	Index       int    `json:"index"`
+	Description string `json:"description"`
+	Affordable  bool   `json:"affordable"`
+}
+
+func newStateView(engine *Engine) stateView {
+	state := stateView{
+		World:     engine.Current(),
+		Decisions: make([]decisionView, 0),
+		Result:    engine.Result(),
+	}
+	i := 0
+	for _, decision := range engine.Decisions() {
+		d := decisionView{Description: decision.Description}
+		for _, choice := range decision.Choices {
+			d.Choices = append(d.Choices, choiceView{
+				Index:       i,
+				Description: choice.Description,
+				Affordable:  engine.CanAfford(choice),
+			})
+			i++
+		}
+		state.Decisions = append(state.Decisions, d)
+	}
+	return state
+}
+
+// chooseIndex makes the choice numbered index across the offered decisions.
+func chooseIndex(engine *Engine, index int) error {
+	choice, ok := choiceAt(engine.Decisions(), index)
+	if !ok {
+		return fmt.Errorf("no choice %d", index)
+	}
+	return engine.Choose(choice)
+}
+
+// choiceAt returns the choice numbered index across decisions, or false if
+// there's no such choice.
+func choiceAt(decisions []Decision, index int) (Choice, bool) {
+	choices := offeredChoices(decisions)
+	if index < 0 || index >= len(choices) {
+		return Choice{}, false
+	}
+	return choices[index], true
+}
+
+// offeredChoices flattens the choices of decisions in the order they are
+// displayed.
+func offeredChoices(decisions []Decision) []Choice {
+	choices := make([]Choice, 0)
+	for _, decision := range decisions {
+		choices = append(choices, decision.Choices...)
+	}
+	return choices
+}
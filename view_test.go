@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestChoiceAt(t *testing.T) {
+	decisions := []Decision{
+		{Description: "Tax", Choices: []Choice{{Description: "Raise"}, {Description: "Lower"}}},
+		{Description: "Empty"},
+		{Description: "Coup", Choices: []Choice{{Description: "Stage"}}},
+	}
+	tests := []struct {
+		index int
+		want  string
+		ok    bool
+	}{
+		{0, "Raise", true},
+		{1, "Lower", true},
+		{2, "Stage", true},
+		{3, "", false},
+		{8, "", false},
+		{-1, "", false},
+	}
+	for _, test := range tests {
+		choice, ok := choiceAt(decisions, test.index)
+		if ok != test.ok || choice.Description != test.want {
+			t.Errorf("index %v: got %q, %v, want %q, %v", test.index, choice.Description, ok, test.want, test.ok)
+		}
+	}
+	if _, ok := choiceAt(nil, 0); ok {
+		t.Errorf("got a choice without decisions")
+	}
+}
+
+func TestChoiceAtAcrossTurns(t *testing.T) {
+	turns := []struct {
+		offered []Decision
+		index   int
+		want    string
+	}{
+		{[]Decision{{Description: "Tax", Choices: []Choice{{Description: "Raise"}, {Description: "Lower"}}}}, 1, "Lower"},
+		{[]Decision{{Description: "Coup", Choices: []Choice{{Description: "Stage"}, {Description: "Crush"}}}}, 1, "Crush"},
+		{[]Decision{{Description: "War", Choices: []Choice{{Description: "Declare"}}}}, 0, "Declare"},
+	}
+	// offered is swapped between turns just like in consoleUI, while the
+	// handler reading it stays the same.
+	var offered []Decision
+	choose := func(index int) string {
+		choice, _ := choiceAt(offered, index)
+		return choice.Description
+	}
+	for i, turn := range turns {
+		offered = turn.offered
+		if got := choose(turn.index); got != turn.want {
+			t.Errorf("turn %v: got %q, want %q", i, got, turn.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// serveHTTP serves a web UI for engine on addr.
+func serveHTTP(engine *Engine, addr string) error {
+	return http.ListenAndServe(addr, newWebHandler(engine))
+}
+
+type webHandler struct {
+	mu     sync.Mutex
+	engine *Engine
+}
+
+func newWebHandler(engine *Engine) http.Handler {
+	h := &webHandler{engine: engine}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.page)
+	mux.HandleFunc("/state", h.state)
+	mux.HandleFunc("/choose", h.choose)
+	return mux
+}
+
+func (h *webHandler) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, webPage)
+}
+
+func (h *webHandler) state(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeState(w)
+}
+
+func (h *webHandler) choose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Index int `json:"index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := chooseIndex(h.engine, req.Index); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeState(w)
+}
+
+func (h *webHandler) writeState(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newStateView(h.engine))
+}
+
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Politika</title>
+</head>
+<body>
+<div id="decisions"></div>
+<pre id="world"></pre>
+<script>
+function render(state) {
+  var decisions = document.getElementById("decisions");
+  decisions.innerHTML = "";
+  state.decisions.forEach(function(d) {
+    var p = document.createElement("p");
+    p.textContent = d.description + " ";
+    (d.choices || []).forEach(function(c) {
+      var b = document.createElement("button");
+      b.textContent = c.description;
+      b.disabled = !c.affordable;
+      b.onclick = function() { choose(c.index); };
+      p.appendChild(b);
+    });
+    decisions.appendChild(p);
+  });
+  if (state.result) {
+    decisions.textContent = "Game over: " + state.result.Outcome;
+  }
+  document.getElementById("world").textContent = JSON.stringify(state.world, null, 2);
+}
+function choose(index) {
+  fetch("/choose", {method: "POST", body: JSON.stringify({index: index})})
+    .then(function(r) { return r.json(); }).then(render);
+}
+fetch("/state").then(function(r) { return r.json(); }).then(render);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebHandler(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newWebHandler(e)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		money  int
+		turn   int
+	}{
+		{"GET", "/state", "", http.StatusOK, 4000, 0},
+		{"POST", "/choose", `{"index": 1}`, http.StatusOK, 3900, 1},
+		{"POST", "/choose", `{"index": 0}`, http.StatusOK, 4000, 2},
+		{"GET", "/state", "", http.StatusOK, 4000, 2},
+		{"POST", "/choose", `{"index": 2}`, http.StatusBadRequest, 0, 0},
+		{"POST", "/choose", `{"index": -1}`, http.StatusBadRequest, 0, 0},
+		{"POST", "/choose", `not json`, http.StatusBadRequest, 0, 0},
+		{"GET", "/choose", "", http.StatusMethodNotAllowed, 0, 0},
+		{"POST", "/state", "", http.StatusMethodNotAllowed, 0, 0},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("%v %v %v: got status %v, want %v", test.method, test.path, test.body, rec.Code, test.code)
+			continue
+		}
+		if test.code != http.StatusOK {
+			continue
+		}
+		var state stateView
+		if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+			t.Fatal(err)
+		}
+		if state.World.Resources["Money"] != test.money || state.World.Turn != test.turn {
+			t.Errorf("%v %v %v: got Money %v on turn %v, want %v on turn %v",
+				test.method, test.path, test.body, state.World.Resources["Money"], state.World.Turn, test.money, test.turn)
+		}
+		if len(state.Decisions) != 1 || len(state.Decisions[0].Choices) != 2 || state.Decisions[0].Choices[1].Index != 1 {
+			t.Errorf("%v %v %v: got decisions %+v, want Tax with two choices", test.method, test.path, test.body, state.Decisions)
+		}
+	}
+}
+
+func TestWebHandlerPage(t *testing.T) {
+	e, err := NewEngine(taxScenario(t), GameConfig{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newWebHandler(e)
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/", http.StatusOK},
+		{"/missing", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.code {
+			t.Errorf("%v: got status %v, want %v", test.path, rec.Code, test.code)
+		}
+	}
+}